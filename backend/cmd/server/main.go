@@ -9,12 +9,18 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"anomaly_detection_system/backend/internal/config"
 	"anomaly_detection_system/backend/internal/filter"
 	"anomaly_detection_system/backend/internal/handler"
+	"anomaly_detection_system/backend/internal/metrics"
 	"anomaly_detection_system/backend/internal/model"
+	"anomaly_detection_system/backend/internal/mqtt"
+	"anomaly_detection_system/backend/internal/naming"
 	"anomaly_detection_system/backend/internal/pipeline"
+	"anomaly_detection_system/backend/internal/training"
+	"anomaly_detection_system/backend/internal/webrtc"
 	"anomaly_detection_system/backend/internal/ws"
 )
 
@@ -33,28 +39,63 @@ func main() {
 	}
 
 	// 创建通道
-	frameChan := make(chan *pipeline.Frame, 30)            // 视频帧通道
-	resultChan := make(chan *pipeline.DetectionResult, 30) // 检测结果通道
-	alertChan := make(chan *ws.AlertMessage, 100)          // 报警消息通道
+	frameChan := make(chan *pipeline.Frame, 30)                     // 视频帧通道
+	detectionResultChan := make(chan *pipeline.DetectionResult, 30) // 检测后端产出的原始结果通道
+	alertChan := make(chan *ws.AlertMessage, 100)                   // WebSocket 消费的报警消息通道
+	mqttAlertChan := make(chan *ws.AlertMessage, 100)               // MQTT 发布者消费的报警消息通道
+	clipAlertChan := make(chan *ws.AlertMessage, 100)               // 录像片段记录器消费的报警消息通道
+
+	// 检测结果扇出器：WebSocket 推流、WHIP 会话、自适应帧率控制器、报警处理各自订阅一路，
+	// 互不阻塞，任一订阅者消费不及时只丢弃它自己的那份拷贝
+	resultFanout := pipeline.NewResultFanout(detectionResultChan)
+	resultChan := resultFanout.Subscribe("websocket", 30)
+	webrtcResultChan := resultFanout.Subscribe("webrtc_whip", 30)
+	fpsResultChan := resultFanout.Subscribe("adaptive_fps", 30)
+	alertResultChan := resultFanout.Subscribe("alert_processor", 30)
+	clipResultChan := resultFanout.Subscribe("clip_recorder", 30)
 
 	// 创建组件
-	videoCapture := pipeline.NewVideoCapture(cfg, frameChan)
-	grpcClient := pipeline.NewGRPCClient(cfg, frameChan, resultChan)
+	videoCapture := pipeline.NewCaptureBackend(cfg, frameChan)
+	captureManager := pipeline.NewCaptureManager(cfg, frameChan) // 多摄像头场景，与 videoCapture 共享 frameChan
+	detectionBackend := pipeline.NewDetectionBackend(cfg, frameChan, detectionResultChan)
 	wsServer := ws.NewWebSocketServer(cfg, resultChan, alertChan)
-	alertFilter := filter.NewAlertFilter(cfg)
-	httpHandler := handler.NewHandler(cfg, grpcClient, videoCapture)
+	webrtcServer := webrtc.NewServer(cfg)
+	nodeRegistry := naming.NewRegistry(cfg) // 多节点分片注册表，cfg.Cluster.Enable 为 false 时不生效
+	alertFilter := filter.NewAlertFilter(cfg, nodeRegistry)
+	trainingScheduler := training.NewScheduler(cfg, detectionBackend)
+	httpHandler := handler.NewHandler(cfg, detectionBackend, videoCapture, captureManager, trainingScheduler, resultFanout, alertFilter)
+	mqttPublisher := mqtt.NewPublisher(cfg, videoCapture, captureManager, detectionBackend, trainingScheduler, mqttAlertChan)
+	clipRecorder := pipeline.NewClipRecorder(cfg)
 
 	// 启动组件
 	log.Println("正在启动各组件...")
 
-	// 启动 gRPC 客户端
-	if err := grpcClient.Start(); err != nil {
-		log.Printf("警告: gRPC 客户端启动失败: %v (AI 服务可能未启动)", err)
+	// 启动检测后端
+	if err := detectionBackend.Start(); err != nil {
+		log.Printf("警告: %s 检测后端启动失败: %v (AI 服务可能未启动)", detectionBackend.Name(), err)
+	}
+
+	// 启动训练调度器
+	if err := trainingScheduler.Start(); err != nil {
+		log.Printf("警告: 训练调度器启动失败: %v", err)
 	}
 
 	// 启动 WebSocket 服务
 	wsServer.Start()
 
+	// 启动检测结果扇出循环
+	go resultFanout.Run()
+
+	// 启动 WHIP 检测叠加层分发协程
+	go webrtcServer.Run(webrtcResultChan)
+
+	// 启动自适应帧率控制协程：场景持续空闲时降帧，检测到活动后恢复
+	go runAdaptiveFPS(cfg, videoCapture, captureManager, fpsResultChan)
+
+	// 启动录像片段记录器：持续喂入帧环形缓冲，报警触发时另行拼接预录+续录窗口落盘
+	go feedClipRecorder(clipRecorder, clipResultChan)
+	go clipTriggerLoop(cfg, clipRecorder, clipAlertChan)
+
 	// 启动视频采集（如果配置了视频源）
 	if cfg.Video.RTSPUrl != "" || cfg.Video.LocalPath != "" {
 		if err := videoCapture.Start(); err != nil {
@@ -62,8 +103,26 @@ func main() {
 		}
 	}
 
+	// 启动多摄像头采集（如果配置了 Cameras）
+	if len(cfg.GetCameras()) > 0 {
+		if err := captureManager.Start(); err != nil {
+			log.Printf("警告: 多摄像头采集启动失败: %v", err)
+		}
+	}
+
 	// 启动报警处理协程
-	go processAlerts(resultChan, alertFilter, alertChan)
+	go processAlerts(alertResultChan, alertFilter, alertChan, mqttAlertChan, clipAlertChan)
+
+	// 启动 QPS 摘要日志协程，给未接入 Prometheus 的运维提供兜底可见性
+	go metrics.LogQPSLoop()
+
+	// 启动 MQTT 发布者（仅在 cfg.MQTT.Enable 为 true 时真正连接 broker）
+	if err := mqttPublisher.Start(); err != nil {
+		log.Printf("警告: MQTT 发布者启动失败: %v", err)
+	}
+
+	// 启动集群分片节点注册（仅在 cfg.Cluster.Enable 为 true 时上报心跳、参与哈希环）
+	nodeRegistry.Start()
 
 	// 设置 Gin 路由
 	gin.SetMode(gin.ReleaseMode)
@@ -88,6 +147,7 @@ func main() {
 		api.GET("/config", httpHandler.GetAllConfig)
 		api.GET("/config/video", httpHandler.GetVideoConfig)
 		api.POST("/config/video", httpHandler.UpdateVideoConfig)
+		api.GET("/video/probe", httpHandler.ProbeVideoSource)
 		api.GET("/config/ai", httpHandler.GetAIConfig)
 		api.POST("/config/ai", httpHandler.UpdateAIConfig)
 		api.GET("/config/filter", httpHandler.GetFilterConfig)
@@ -101,12 +161,33 @@ func main() {
 		// 训练接口
 		api.GET("/training/status", httpHandler.GetTrainingStatus)
 		api.POST("/training/trigger", httpHandler.TriggerTraining)
+		api.POST("/training/:id/cancel", httpHandler.CancelTraining)
+		api.GET("/training/schedule", httpHandler.GetTrainingSchedules)
+		api.POST("/training/schedule", httpHandler.AddTrainingSchedule)
+		api.DELETE("/training/schedule/:id", httpHandler.DeleteTrainingSchedule)
+
+		api.GET("/alert-strategies", httpHandler.GetAlertStrategies)
+		api.POST("/alert-strategies", httpHandler.AddAlertStrategy)
+		api.DELETE("/alert-strategies/:id", httpHandler.DeleteAlertStrategy)
+
+		api.GET("/filter/state", httpHandler.GetFilterState)
+		api.DELETE("/filter/state", httpHandler.ResetFilterState)
 
 		// 样本接口
 		api.GET("/samples/pending", httpHandler.GetPendingSamples)
 
 		// 系统状态
 		api.GET("/status", httpHandler.GetSystemStatus)
+
+		// 多摄像头接口
+		api.GET("/cameras", httpHandler.GetCameras)
+		api.POST("/cameras", httpHandler.AddCamera)
+		api.DELETE("/cameras/:id", httpHandler.RemoveCamera)
+		api.POST("/cameras/:id/restart", httpHandler.RestartCamera)
+
+		// 报警录像片段接口
+		api.GET("/clips", httpHandler.GetClips)
+		api.GET("/clips/:id", httpHandler.GetClip)
 	}
 
 	// WebSocket 路由
@@ -114,6 +195,39 @@ func main() {
 		wsServer.HandleWebSocket(c.Writer, c.Request)
 	})
 
+	// WHIP 推流路由（WebSocket 之外的低延迟播放通道）
+	router.POST("/webrtc/whip/:camera_id", webrtcServer.HandleOffer)
+	router.DELETE("/webrtc/whip/:camera_id/:session_id", webrtcServer.HandleDelete)
+
+	// 集群节点间转发路由：哈希环上不归本节点负责的检测结果/再平衡时交接的轨迹状态由对端节点调用，
+	// 不对外网暴露、不走 /api 分组
+	router.POST("/internal/detections/forward", func(c *gin.Context) {
+		var result pipeline.DetectionResult
+		if err := c.BindJSON(&result); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, alert := range alertFilter.ProcessDetections(&result) {
+			forwardAlert(alert, []chan *ws.AlertMessage{alertChan, mqttAlertChan, clipAlertChan})
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	router.POST("/internal/state/import", func(c *gin.Context) {
+		var payload filter.StateImportPayload
+		if err := c.BindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := alertFilter.ImportTrackedAlert(payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+
+	// Prometheus 指标
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// 健康检查
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -148,18 +262,108 @@ func main() {
 
 	// 停止组件
 	videoCapture.Stop()
-	grpcClient.Stop()
+	captureManager.Stop()
+	detectionBackend.Stop()
+	trainingScheduler.Stop()
 	wsServer.Stop()
+	webrtcServer.Stop()
+	mqttPublisher.Stop()
+	alertFilter.Stop()
 
 	log.Println("服务已关闭")
 }
 
-// processAlerts 处理检测结果，生成报警
-func processAlerts(resultChan chan *pipeline.DetectionResult, alertFilter *filter.AlertFilter, alertChan chan *ws.AlertMessage) {
-	// 创建一个新的通道来接收结果（不影响 WebSocket 广播）
-	// 这里简化实现：直接监听，实际应该用扇出模式
+// runAdaptiveFPS 按摄像头维护独立的 FPSController：单摄像头场景（CameraID 为空）对应 videoCapture，
+// 多摄像头场景按 Frame.CameraID 在 captureManager 中查找对应的采集后端；IdleWindowSeconds<=0 的摄像头不启用
+func runAdaptiveFPS(cfg *config.Config, videoCapture pipeline.CaptureBackend, captureManager *pipeline.CaptureManager, in <-chan *pipeline.DetectionResult) {
+	controllers := make(map[string]*pipeline.FPSController)
+
+	for result := range in {
+		if result.Frame == nil {
+			continue
+		}
+		cameraID := result.Frame.CameraID
+
+		var videoConfig config.VideoConfig
+		var backend pipeline.CaptureBackend
+		if cameraID == "" {
+			videoConfig = cfg.GetVideo()
+			backend = videoCapture
+		} else {
+			vc, ok := cfg.GetCamera(cameraID)
+			if !ok {
+				continue
+			}
+			cam, ok := captureManager.Camera(cameraID)
+			if !ok {
+				continue
+			}
+			videoConfig = vc
+			backend = cam
+		}
+
+		if videoConfig.IdleWindowSeconds <= 0 {
+			continue
+		}
+
+		controller, ok := controllers[cameraID]
+		if !ok {
+			controller = pipeline.NewFPSController(backend, videoConfig.FPS, videoConfig.IdleFPS, time.Duration(videoConfig.IdleWindowSeconds)*time.Second)
+			controllers[cameraID] = controller
+		}
+		controller.Feed(result, videoConfig.MotionIoUThreshold)
+	}
+}
+
+// feedClipRecorder 把检测结果扇出的一路持续喂给 ClipRecorder 维护的帧环形缓冲
+func feedClipRecorder(recorder *pipeline.ClipRecorder, in <-chan *pipeline.DetectionResult) {
+	for result := range in {
+		recorder.Feed(result)
+	}
+}
+
+// clipTriggerLoop 消费报警消息，按报警所属摄像头当前配置的采集帧率触发一段录像；
+// 未启用报警录像时 ClipRecorder.TriggerClip 内部直接跳过
+func clipTriggerLoop(cfg *config.Config, recorder *pipeline.ClipRecorder, in <-chan *ws.AlertMessage) {
+	for alert := range in {
+		fps := cfg.GetVideo().FPS
+		if alert.CameraID != "" {
+			if vc, ok := cfg.GetCamera(alert.CameraID); ok {
+				fps = vc.FPS
+			}
+		}
+		recorder.TriggerClip(alert.CameraID, alert.FrameID, time.UnixMilli(alert.Timestamp), fps)
+	}
+}
+
+// processAlerts 消费检测结果，经 alertFilter 过滤后把需要报警的检测分发给多个报警消费通道
+// （WebSocket 广播、MQTT 发布等），任一通道已满则只丢弃该份拷贝，不影响其余消费者
+func processAlerts(resultChan <-chan *pipeline.DetectionResult, alertFilter *filter.AlertFilter, outs ...chan *ws.AlertMessage) {
 	log.Println("[AlertProcessor] 报警处理协程启动")
 
-	// 注意：由于 resultChan 同时被 wsServer 和这里使用，
-	// 实际实现中应该使用扇出模式。这里仅作示例。
+	go forwardAlerts(alertFilter.ResolvedAlerts(), outs)
+
+	for result := range resultChan {
+		for _, alert := range alertFilter.ProcessDetections(result) {
+			forwardAlert(alert, outs)
+		}
+	}
+}
+
+// forwardAlerts 把 resolved 事件通道中的消息持续转发给多个报警消费通道
+func forwardAlerts(in <-chan *ws.AlertMessage, outs []chan *ws.AlertMessage) {
+	for alert := range in {
+		forwardAlert(alert, outs)
+	}
+}
+
+// forwardAlert 把一条报警消息非阻塞地分发给多个报警消费通道，通道已满时丢弃并记录日志
+func forwardAlert(alert *ws.AlertMessage, outs []chan *ws.AlertMessage) {
+	for _, out := range outs {
+		select {
+		case out <- alert:
+		default:
+			log.Println("[AlertProcessor] 报警通道已满，丢弃一条报警")
+		}
+	}
 }
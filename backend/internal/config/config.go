@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"sync"
 )
 
@@ -11,9 +12,12 @@ type Config struct {
 	// 服务器配置
 	Server ServerConfig `yaml:"server"`
 
-	// 视频源配置
+	// 视频源配置（单摄像头场景，向后兼容；多摄像头场景见 Cameras）
 	Video VideoConfig `yaml:"video"`
 
+	// 多摄像头配置列表，每个条目由唯一的 CameraID 标识
+	Cameras []VideoConfig `yaml:"cameras" json:"cameras"`
+
 	// AI 服务配置
 	AI AIConfig `yaml:"ai"`
 
@@ -25,6 +29,18 @@ type Config struct {
 
 	// 数据库配置
 	Database DatabaseConfig `yaml:"database"`
+
+	// MQTT 发布订阅配置
+	MQTT MQTTConfig `yaml:"mqtt"`
+
+	// 报警录像片段配置
+	Clip ClipConfig `yaml:"clip"`
+
+	// 报警通知分发配置
+	Notify NotifyConfig `yaml:"notify"`
+
+	// 多节点分片配置
+	Cluster ClusterConfig `yaml:"cluster"`
 }
 
 // ServerConfig 服务器配置
@@ -36,10 +52,24 @@ type ServerConfig struct {
 
 // VideoConfig 视频源配置
 type VideoConfig struct {
+	CameraID   string `yaml:"camera_id" json:"camera_id"`     // 摄像头唯一标识，多摄像头场景下使用；单摄像头场景可为空
+	InputMode  string `yaml:"input_mode" json:"input_mode"`   // 输入模式: "local" | "rtsp" | "usb"；为空时回退到 SourceType
 	SourceType string `yaml:"source_type" json:"source_type"` // "rtsp" 或 "local"
-	RTSPUrl    string `yaml:"rtsp_url" json:"rtsp_url"`       // RTSP 地址
+	Backend    string `yaml:"backend" json:"backend"`         // 采集后端: "ffmpeg" | "gocv" | "gortsplib2"
+	RTSPUrl    string `yaml:"rtsp_url" json:"rtsp_url"`       // RTSP 地址，不含凭据
+	Username   string `yaml:"username" json:"username"`       // RTSP 认证用户名
+	Password   string `yaml:"password" json:"-"`              // RTSP 认证密码，不通过 API 回显
+	Transport  string `yaml:"transport" json:"transport"`     // RTSP 传输模式: "tcp" | "udp" | "auto"
 	LocalPath  string `yaml:"local_path" json:"local_path"`   // 本地文件路径
 	FPS        int    `yaml:"fps" json:"fps"`                 // 采集帧率 (30 或 60)
+
+	ReconnectBackoffMs int `yaml:"reconnect_backoff_ms" json:"reconnect_backoff_ms"` // 重连退避基数（毫秒）
+	ReadTimeoutMs      int `yaml:"read_timeout_ms" json:"read_timeout_ms"`           // 建连/读取超时（毫秒）
+	StallDetectionMs   int `yaml:"stall_detection_ms" json:"stall_detection_ms"`     // 无帧多久后判定为卡死并重启（毫秒）
+
+	IdleFPS            int     `yaml:"idle_fps" json:"idle_fps"`                         // 无活动时的"休眠"帧率，<=0 时默认 2
+	IdleWindowSeconds  int     `yaml:"idle_window_seconds" json:"idle_window_seconds"`   // 无活动多久后降到 IdleFPS，<=0 表示不启用自适应帧率
+	MotionIoUThreshold float32 `yaml:"motion_iou_threshold" json:"motion_iou_threshold"` // 前后两帧同类别检测框 IoU 低于该值视为发生了移动
 }
 
 // AIConfig AI 服务参数配置
@@ -48,6 +78,12 @@ type AIConfig struct {
 	EntropyThreshold    float32 `yaml:"entropy_threshold" json:"entropy_threshold"`       // 熵值阈值
 	NMSIoUThreshold     float32 `yaml:"nms_iou_threshold" json:"nms_iou_threshold"`       // NMS IoU 阈值
 	InputSize           int     `yaml:"input_size" json:"input_size"`                     // 输入图像尺寸
+
+	BackendType    string `yaml:"backend_type" json:"backend_type"`         // 检测后端类型: "grpc" | "http" | "local" | "multi"
+	HTTPEndpoint   string `yaml:"http_endpoint" json:"http_endpoint"`       // HTTPBackend 的 REST 地址
+	LocalModelPath string `yaml:"local_model_path" json:"local_model_path"` // LocalONNXBackend 的模型路径
+
+	MultiBackendTypes []string `yaml:"multi_backend_types" json:"multi_backend_types"` // BackendType 为 "multi" 时要并发请求的子后端类型列表（取值同 BackendType，不含 "multi"）
 }
 
 // FilterConfig 过滤器配置
@@ -56,6 +92,10 @@ type FilterConfig struct {
 	TimeWindowSeconds   int     `yaml:"time_window_seconds" json:"time_window_seconds"`     // 时间窗口（秒）
 	EnableAlertPush     bool    `yaml:"enable_alert_push" json:"enable_alert_push"`         // 启用报警推送
 	AutoSaveSample      bool    `yaml:"auto_save_sample" json:"auto_save_sample"`           // 自动保存样本
+	ResolveSeconds      int     `yaml:"resolve_seconds" json:"resolve_seconds"`             // 内置默认策略的轨迹消失多久后判定为 resolved（秒），<=0 时取内置默认值
+
+	StatePath                string `yaml:"state_path" json:"state_path"`                                   // 活跃轨迹状态快照文件路径，为空时取内置默认值
+	StateSaveIntervalSeconds int    `yaml:"state_save_interval_seconds" json:"state_save_interval_seconds"` // 状态快照周期（秒），<=0 时取内置默认值
 }
 
 // TrainingConfig 训练配置
@@ -70,6 +110,74 @@ type DatabaseConfig struct {
 	Path string `yaml:"path"` // SQLite 数据库文件路径
 }
 
+// MQTTConfig MQTT 发布订阅配置：启用后把报警/状态发布到 broker，并订阅 commands/{camera_id} 接收外部指令
+type MQTTConfig struct {
+	Enable                bool   `yaml:"enable" json:"enable"`                                   // 是否启用 MQTT
+	BrokerURL             string `yaml:"broker_url" json:"broker_url"`                           // 形如 tcp://host:1883 或 ssl://host:8883
+	ClientID              string `yaml:"client_id" json:"client_id"`                             // 连接 broker 使用的 client id
+	Username              string `yaml:"username" json:"username"`                               // 认证用户名，为空表示匿名连接
+	Password              string `yaml:"password" json:"-"`                                      // 认证密码，不通过 API 回显
+	TLS                   bool   `yaml:"tls" json:"tls"`                                         // BrokerURL 为 ssl:// 时是否校验服务端证书
+	BaseTopic             string `yaml:"base_topic" json:"base_topic"`                           // 主题前缀，实际主题为 {base_topic}/alerts/{camera_id} 等
+	QoS                   byte   `yaml:"qos" json:"qos"`                                         // 发布/订阅使用的 QoS 等级 (0/1/2)
+	StatusIntervalSeconds int    `yaml:"status_interval_seconds" json:"status_interval_seconds"` // 状态上报周期（秒），<=0 时默认 10
+}
+
+// ClipConfig 报警录像片段配置：报警触发时把前后一段时间的帧编码为 MP4 落盘，作为取证视频证据
+type ClipConfig struct {
+	Enable            bool `yaml:"enable" json:"enable"`                           // 是否启用报警录像
+	PreRollSeconds    int  `yaml:"pre_roll_seconds" json:"pre_roll_seconds"`       // 报警前回溯时长（秒），<=0 时默认 10
+	PostRollSeconds   int  `yaml:"post_roll_seconds" json:"post_roll_seconds"`     // 报警后继续录制时长（秒），<=0 时默认 5
+	RingBufferSeconds int  `yaml:"ring_buffer_seconds" json:"ring_buffer_seconds"` // 每路摄像头维护的帧环形缓冲时长（秒），<=0 时默认等于 PreRollSeconds+PostRollSeconds
+}
+
+// NotifyChannelConfig 一个通知渠道的配置，Type 决定其余字段按哪种渠道解释
+type NotifyChannelConfig struct {
+	Name    string `yaml:"name" json:"name"`       // 渠道标识，用于 NotificationLog 中区分来源
+	Type    string `yaml:"type" json:"type"`       // "webhook" | "email" | "dingtalk" | "kafka"
+	Enabled bool   `yaml:"enabled" json:"enabled"` // 是否启用该渠道
+
+	MaxRetries int `yaml:"max_retries" json:"max_retries"` // 单条报警的最大重试次数，<=0 时默认 3
+	QueueSize  int `yaml:"queue_size" json:"queue_size"`   // 该渠道的待发送队列容量，<=0 时默认 50，队满后丢弃最旧的一条
+
+	// webhook: JSON POST 报警消息
+	URL string `yaml:"url" json:"url"`
+
+	// email: 通过 SMTP 发送
+	SMTPHost  string   `yaml:"smtp_host" json:"smtp_host"`
+	SMTPPort  int      `yaml:"smtp_port" json:"smtp_port"`
+	SMTPUser  string   `yaml:"smtp_user" json:"smtp_user"`
+	SMTPPass  string   `yaml:"smtp_pass" json:"-"`
+	EmailFrom string   `yaml:"email_from" json:"email_from"`
+	EmailTo   []string `yaml:"email_to" json:"email_to"`
+
+	// dingtalk: 自定义机器人 webhook，Secret 非空时启用加签
+	WebhookURL string `yaml:"webhook_url" json:"-"`
+	Secret     string `yaml:"secret" json:"-"`
+
+	// kafka: 生产者
+	Brokers []string `yaml:"brokers" json:"brokers"`
+	Topic   string   `yaml:"topic" json:"topic"`
+}
+
+// NotifyConfig 报警通知分发配置：AlertFilter 命中报警后按此处列出的渠道异步分发
+type NotifyConfig struct {
+	DryRun   bool                  `yaml:"dry_run" json:"dry_run"` // 仅记录路由结果，不实际发送，用于联调通知规则
+	Channels []NotifyChannelConfig `yaml:"channels" json:"channels"`
+}
+
+// ClusterConfig 多节点分片配置：启用后通过一致性哈希环把不同 stream_id/CameraID 的检测结果
+// 固定分配给集群中的某一节点处理，未命中本地的检测结果转发给所属节点，避免多节点场景下
+// 同一目标被重复判定为报警
+type ClusterConfig struct {
+	Enable                   bool   `yaml:"enable" json:"enable"`                                         // 是否启用分片，关闭时所有检测结果都在本地处理
+	SelfEndpoint             string `yaml:"self_endpoint" json:"self_endpoint"`                           // 本节点对外暴露的地址，形如 http://host:8080，写入心跳表并用于一致性哈希环
+	VirtualReplicas          int    `yaml:"virtual_replicas" json:"virtual_replicas"`                     // 每个节点在哈希环上的虚拟节点数，<=0 时默认 100
+	HeartbeatIntervalSeconds int    `yaml:"heartbeat_interval_seconds" json:"heartbeat_interval_seconds"` // 上报心跳的周期（秒），<=0 时默认 5
+	NodeTimeoutSeconds       int    `yaml:"node_timeout_seconds" json:"node_timeout_seconds"`             // 心跳多久未更新视为节点已下线（秒），<=0 时默认 15
+	RefreshIntervalSeconds   int    `yaml:"refresh_interval_seconds" json:"refresh_interval_seconds"`     // 刷新存活节点列表、重建哈希环的周期（秒），<=0 时默认 5
+}
+
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
@@ -79,22 +187,34 @@ func DefaultConfig() *Config {
 			GRPCAddress:   "localhost:50051",
 		},
 		Video: VideoConfig{
-			SourceType: "rtsp",
-			RTSPUrl:    "",
-			LocalPath:  "",
-			FPS:        30,
+			SourceType:         "rtsp",
+			Backend:            "ffmpeg",
+			RTSPUrl:            "",
+			Transport:          "tcp",
+			LocalPath:          "",
+			FPS:                30,
+			ReconnectBackoffMs: 1000,
+			ReadTimeoutMs:      5000,
+			StallDetectionMs:   10000,
+			IdleFPS:            2,
+			IdleWindowSeconds:  180,
+			MotionIoUThreshold: 0.9,
 		},
 		AI: AIConfig{
 			ConfidenceThreshold: 0.5,
 			EntropyThreshold:    0.5,
 			NMSIoUThreshold:     0.8,
 			InputSize:           640,
+			BackendType:         "grpc",
 		},
 		Filter: FilterConfig{
-			SpatialIoUThreshold: 0.5,
-			TimeWindowSeconds:   60,
-			EnableAlertPush:     true,
-			AutoSaveSample:      true,
+			SpatialIoUThreshold:      0.5,
+			TimeWindowSeconds:        60,
+			EnableAlertPush:          true,
+			AutoSaveSample:           true,
+			ResolveSeconds:           15,
+			StatePath:                "",
+			StateSaveIntervalSeconds: 30,
 		},
 		Training: TrainingConfig{
 			TriggerThreshold:   100,
@@ -104,7 +224,128 @@ func DefaultConfig() *Config {
 		Database: DatabaseConfig{
 			Path: "/app/data/detection.db",
 		},
+		MQTT: MQTTConfig{
+			Enable:                false,
+			BrokerURL:             "tcp://localhost:1883",
+			ClientID:              "anomaly-detection-backend",
+			BaseTopic:             "anomaly",
+			QoS:                   1,
+			StatusIntervalSeconds: 10,
+		},
+		Clip: ClipConfig{
+			Enable:            false,
+			PreRollSeconds:    10,
+			PostRollSeconds:   5,
+			RingBufferSeconds: 15,
+		},
+		Notify: NotifyConfig{
+			DryRun:   false,
+			Channels: nil,
+		},
+		Cluster: ClusterConfig{
+			Enable:                   false,
+			VirtualReplicas:          100,
+			HeartbeatIntervalSeconds: 5,
+			NodeTimeoutSeconds:       15,
+			RefreshIntervalSeconds:   5,
+		},
+	}
+}
+
+// VideoConfigProvider 提供单路视频源配置的只读访问。
+// *Config（单摄像头场景）与 CameraHandle（多摄像头场景）都实现该接口，
+// pipeline 包的采集后端只依赖它，不关心配置来自哪种场景。
+type VideoConfigProvider interface {
+	GetVideo() VideoConfig
+}
+
+// CameraHandle 绑定到 Config.Cameras 中某一路摄像头的配置视图，
+// 每次 GetVideo 都重新从 Cameras 读取，从而感知配置更新。
+type CameraHandle struct {
+	config   *Config
+	cameraID string
+}
+
+// GetVideo 返回该摄像头当前的配置，若摄像头已被删除则返回仅含 CameraID 的零值配置
+func (h *CameraHandle) GetVideo() VideoConfig {
+	h.config.mu.RLock()
+	defer h.config.mu.RUnlock()
+	for _, cam := range h.config.Cameras {
+		if cam.CameraID == h.cameraID {
+			return cam
+		}
+	}
+	return VideoConfig{CameraID: h.cameraID}
+}
+
+// CameraHandle 返回绑定到指定摄像头 ID 的配置视图
+func (c *Config) CameraHandle(cameraID string) *CameraHandle {
+	return &CameraHandle{config: c, cameraID: cameraID}
+}
+
+// GetCameras 获取多摄像头配置列表
+func (c *Config) GetCameras() []VideoConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cameras := make([]VideoConfig, len(c.Cameras))
+	copy(cameras, c.Cameras)
+	return cameras
+}
+
+// GetCamera 按 CameraID 查找单个摄像头配置
+func (c *Config) GetCamera(cameraID string) (VideoConfig, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, cam := range c.Cameras {
+		if cam.CameraID == cameraID {
+			return cam, true
+		}
+	}
+	return VideoConfig{}, false
+}
+
+// AddCamera 新增一路摄像头配置，CameraID 不能为空或重复
+func (c *Config) AddCamera(cfg VideoConfig) error {
+	if cfg.CameraID == "" {
+		return fmt.Errorf("camera_id 不能为空")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cam := range c.Cameras {
+		if cam.CameraID == cfg.CameraID {
+			return fmt.Errorf("camera_id %q 已存在", cfg.CameraID)
+		}
+	}
+	c.Cameras = append(c.Cameras, cfg)
+	return nil
+}
+
+// UpdateCamera 更新已存在的摄像头配置
+func (c *Config) UpdateCamera(cameraID string, cfg VideoConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, cam := range c.Cameras {
+		if cam.CameraID == cameraID {
+			cfg.CameraID = cameraID
+			c.Cameras[i] = cfg
+			return nil
+		}
+	}
+	return fmt.Errorf("camera_id %q 不存在", cameraID)
+}
+
+// RemoveCamera 删除一路摄像头配置
+func (c *Config) RemoveCamera(cameraID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, cam := range c.Cameras {
+		if cam.CameraID == cameraID {
+			c.Cameras = append(c.Cameras[:i], c.Cameras[i+1:]...)
+			return nil
+		}
 	}
+	return fmt.Errorf("camera_id %q 不存在", cameraID)
 }
 
 // UpdateVideo 更新视频配置
@@ -135,6 +376,13 @@ func (c *Config) UpdateTraining(cfg TrainingConfig) {
 	c.Training = cfg
 }
 
+// UpdateMQTT 更新 MQTT 配置
+func (c *Config) UpdateMQTT(cfg MQTTConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.MQTT = cfg
+}
+
 // GetVideo 获取视频配置
 func (c *Config) GetVideo() VideoConfig {
 	c.mu.RLock()
@@ -162,3 +410,52 @@ func (c *Config) GetTraining() TrainingConfig {
 	defer c.mu.RUnlock()
 	return c.Training
 }
+
+// GetMQTT 获取 MQTT 配置
+func (c *Config) GetMQTT() MQTTConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MQTT
+}
+
+// UpdateClip 更新报警录像片段配置
+func (c *Config) UpdateClip(cfg ClipConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Clip = cfg
+}
+
+// GetClip 获取报警录像片段配置
+func (c *Config) GetClip() ClipConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Clip
+}
+
+// UpdateNotify 更新报警通知分发配置
+func (c *Config) UpdateNotify(cfg NotifyConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Notify = cfg
+}
+
+// GetNotify 获取报警通知分发配置
+func (c *Config) GetNotify() NotifyConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Notify
+}
+
+// UpdateCluster 更新多节点分片配置
+func (c *Config) UpdateCluster(cfg ClusterConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Cluster = cfg
+}
+
+// GetCluster 获取多节点分片配置
+func (c *Config) GetCluster() ClusterConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Cluster
+}
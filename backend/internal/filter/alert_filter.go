@@ -1,143 +1,388 @@
 package filter
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
 	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/metrics"
+	"anomaly_detection_system/backend/internal/model"
+	"anomaly_detection_system/backend/internal/naming"
+	"anomaly_detection_system/backend/internal/notify"
 	"anomaly_detection_system/backend/internal/pipeline"
 	"anomaly_detection_system/backend/internal/ws"
 )
 
-// ActiveAlert 活跃报警记录
+const (
+	systemConfigStrategyKey = "alert_strategies"
+	strategySyncInterval    = 30 * time.Second // 周期性从 SystemConfig 刷新 strategy_cache，类似 open-falcon Judge 的 SyncStrategies
+	trackCleanupInterval    = 5 * time.Second  // pending/fires 两个状态机的巡检间隔
+
+	defaultStrategyKey    = "default" // 内置默认策略（仅处理不确定目标）使用的分组键
+	defaultStrategyName   = "内置默认策略"
+	defaultResolveSeconds = 15 // FilterConfig.ResolveSeconds 未配置时的兜底值
+
+	resolvedChanSize = 100
+)
+
+// defaultAlertStrategy 表示命中内置默认规则（IsUncertain）时返回给调用方的策略标识，
+// 优先级恒为 0，因此任何自定义策略都会优先于它胜出
+var defaultAlertStrategy = &AlertStrategy{
+	ID:       0,
+	Name:     defaultStrategyName,
+	Priority: 0,
+	Severity: "warning",
+}
+
+// ActiveAlert 是 pending/firing 状态机中一条轨迹的状态，TrackKey 取检测框 ID 的字符串形式，
+// 同一物理目标的检测框 ID 在被追踪期间应保持稳定
 type ActiveAlert struct {
-	ID        int32     // 检测框ID
-	CenterX   float32   // 中心点 X
-	CenterY   float32   // 中心点 Y
-	X1, Y1    float32   // 边界框左上角
-	X2, Y2    float32   // 边界框右下角
-	Timestamp time.Time // 时间戳
+	TrackKey  string
+	X1, Y1    float32
+	X2, Y2    float32
+	FirstSeen time.Time // 首次进入 pending 的时间，用于 FOR 子句判定
+	LastSeen  time.Time // 最近一次被匹配到的时间，用于 resolved 超时判定
+
+	CameraID   string
+	FrameID    int64
+	ClassName  string
+	Confidence float32
+	Entropy    float32
+	StrategyID uint
+	Severity   string
 }
 
-// AlertFilter 报警过滤器（空间及时间抑制）
+// AlertStrategy 一条自定义报警策略：用表达式描述命中条件，拥有独立的冷却时间/优先级/目标通知渠道/
+// 恢复超时，持久化在 SystemConfig 中（键 alert_strategies），由 AlertFilter 周期性刷新到内存中
+type AlertStrategy struct {
+	ID              uint      `json:"id"`
+	Name            string    `json:"name"`
+	Expression      string    `json:"expression"`       // 例如: entropy > 0.7 AND class_name IN ("person","vehicle") FOR 3s
+	CooldownSeconds int       `json:"cooldown_seconds"` // 命中后的冷却时间，期间该策略不再重复进入 pending
+	ResolveSeconds  int       `json:"resolve_seconds"`  // 轨迹消失多久后判定为 resolved，<=0 时复用全局 FilterConfig.ResolveSeconds
+	Priority        int       `json:"priority"`         // 数值越大优先级越高，多个策略同时命中时取最高者
+	Severity        string    `json:"severity"`         // 报警级别，如 info/warning/critical，透传到 ws.AlertMessage
+	NotifyChannel   string    `json:"notify_channel"`   // 目标通知渠道标识，当前仅作为标签透传
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// compiledStrategy 是 strategy_cache 中的一个条目：策略定义 + 编译后的表达式，避免每次求值都重新解析
+type compiledStrategy struct {
+	strategy AlertStrategy
+	compiled *CompiledExpression
+}
+
+// AlertFilter 报警过滤器：按优先级评估自定义策略（全部未命中时回退到内置默认规则），
+// 并用 pending -> firing -> resolved 状态机（仿 nightingale RuleEval）管理每条轨迹的报警生命周期：
+// 首次命中进入 pending，持续满足 FOR 时长后晋升为 firing 并推送一条 firing 消息；
+// 轨迹消失超过 ResolveSeconds 后从 fires 移除，并推送一条配对的 resolved 消息；
+// 每条 firing/resolved 消息都会经 dispatcher 异步转发给 config.NotifyConfig 中配置的通知渠道
 type AlertFilter struct {
-	mu           sync.RWMutex
-	config       *config.Config
-	activeAlerts []*ActiveAlert // 活跃报警列表
+	config *config.Config
+
+	trackMu  sync.Mutex
+	pendings map[string]map[string]*ActiveAlert // key1: 策略标识, key2: 轨迹标识(track key)
+	fires    map[string]map[string]*ActiveAlert
+
+	strategyMu     sync.RWMutex
+	strategies     map[uint]*compiledStrategy // strategy_cache
+	nextStrategyID uint
+
+	cooldownMu sync.Mutex
+	cooldowns  map[string]time.Time // key: cooldownKey(策略, 轨迹)，记录该轨迹最近一次晋升为 firing 的时间
+
+	resolved chan *ws.AlertMessage // resolved 事件输出，由调用方经 ResolvedAlerts() 转发给下游消费者
+
+	dispatcher *notify.Dispatcher // 把每条 firing/resolved 消息异步转发给配置的通知渠道
+
+	registry  *naming.Registry  // 分片成员管理，cfg.GetCluster().Enable 为 false 时为 nil
+	forwarder *naming.Forwarder // 把不归本节点处理的检测结果/轨迹状态转发给所属节点
+
+	stopCh chan struct{} // Stop 时关闭，通知所有后台协程退出
 }
 
-// NewAlertFilter 创建报警过滤器
-func NewAlertFilter(cfg *config.Config) *AlertFilter {
+// NewAlertFilter 创建报警过滤器，从 SystemConfig 恢复已保存的自定义策略，并从磁盘快照恢复
+// 重启前尚未 resolved 的 pendings/fires，避免重启后对已经看过的目标重新发出一遍报警。
+// registry 用于多节点分片场景，按一致性哈希把检测结果路由到所属节点；registry 为 nil 或
+// 未启用分片时，所有检测结果都在本地处理
+func NewAlertFilter(cfg *config.Config, registry *naming.Registry) *AlertFilter {
 	filter := &AlertFilter{
-		config:       cfg,
-		activeAlerts: make([]*ActiveAlert, 0),
+		config:     cfg,
+		pendings:   make(map[string]map[string]*ActiveAlert),
+		fires:      make(map[string]map[string]*ActiveAlert),
+		strategies: make(map[uint]*compiledStrategy),
+		cooldowns:  make(map[string]time.Time),
+		resolved:   make(chan *ws.AlertMessage, resolvedChanSize),
+		dispatcher: notify.NewDispatcher(cfg),
+		registry:   registry,
+		forwarder:  naming.NewForwarder(),
+		stopCh:     make(chan struct{}),
+	}
+
+	if err := filter.loadStrategies(); err != nil {
+		log.Printf("[AlertFilter] 恢复报警策略失败: %v", err)
+	}
+	if err := filter.loadState(); err != nil {
+		log.Printf("[AlertFilter] 恢复状态快照失败: %v", err)
 	}
 
-	// 启动清理协程
 	go filter.cleanupLoop()
+	go filter.syncStrategiesLoop()
+	go filter.saveStateLoop()
+	if filter.registry != nil {
+		go filter.rebalanceLoop()
+	}
 
 	return filter
 }
 
-// cleanupLoop 定期清理过期报警
+// ResolvedAlerts 返回 resolved 事件输出通道，调用方负责转发给 WebSocket/MQTT 等下游消费者
+func (f *AlertFilter) ResolvedAlerts() <-chan *ws.AlertMessage {
+	return f.resolved
+}
+
+// Stop 停止所有后台协程，并在退出前做最后一次状态快照落盘，避免优雅重启时丢失 pendings/fires
+func (f *AlertFilter) Stop() {
+	close(f.stopCh)
+	if f.registry != nil {
+		f.registry.Stop()
+	}
+	if err := f.saveState(); err != nil {
+		log.Printf("[AlertFilter] 退出前保存状态快照失败: %v", err)
+	}
+}
+
+// cleanupLoop 定期巡检 pending/fires 两个状态机，清理过期轨迹并推送 resolved 事件，直到 Stop 被调用
 func (f *AlertFilter) cleanupLoop() {
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(trackCleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		f.cleanup()
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case <-ticker.C:
+			f.cleanup()
+		}
 	}
 }
 
-// cleanup 清理过期的报警记录
+// cleanup 移除长期未再出现的 pending 轨迹；firing 轨迹超时消失则移除并推送 resolved 事件
 func (f *AlertFilter) cleanup() {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	now := time.Now()
 
-	filterConfig := f.config.GetFilter()
-	expireTime := time.Now().Add(-time.Duration(filterConfig.TimeWindowSeconds) * time.Second)
+	f.trackMu.Lock()
+	var toResolve []*ActiveAlert
 
-	newList := make([]*ActiveAlert, 0, len(f.activeAlerts))
-	for _, alert := range f.activeAlerts {
-		if alert.Timestamp.After(expireTime) {
-			newList = append(newList, alert)
+	for sKey, pendings := range f.pendings {
+		resolveWindow := time.Duration(f.resolveSecondsForKey(sKey)) * time.Second
+		for tKey, alert := range pendings {
+			if now.Sub(alert.LastSeen) >= resolveWindow {
+				delete(pendings, tKey)
+			}
+		}
+		if len(pendings) == 0 {
+			delete(f.pendings, sKey)
 		}
 	}
 
-	if len(newList) != len(f.activeAlerts) {
-		log.Printf("[AlertFilter] 清理过期报警: %d -> %d", len(f.activeAlerts), len(newList))
+	for sKey, fires := range f.fires {
+		resolveWindow := time.Duration(f.resolveSecondsForKey(sKey)) * time.Second
+		for tKey, alert := range fires {
+			if now.Sub(alert.LastSeen) >= resolveWindow {
+				delete(fires, tKey)
+				toResolve = append(toResolve, alert)
+			}
+		}
+		if len(fires) == 0 {
+			delete(f.fires, sKey)
+		}
+	}
+	metrics.SetActiveAlerts(f.activeAlertsCountLocked())
+	f.trackMu.Unlock()
+
+	for _, alert := range toResolve {
+		log.Printf("[AlertFilter] 轨迹恢复: strategy=%d, track=%s", alert.StrategyID, alert.TrackKey)
+		metrics.RecordResolved(alert.ClassName)
+		select {
+		case f.resolved <- f.buildAlertMessage(alert, "resolved"):
+		default:
+			log.Println("[AlertFilter] resolved 事件通道已满，丢弃一条恢复事件")
+		}
 	}
 
-	f.activeAlerts = newList
+	metrics.ObserveCleanupDuration(time.Since(now))
 }
 
-// ShouldAlert 检查是否应该发送报警
-// 返回 true 表示应该发送报警，false 表示应该抑制
-func (f *AlertFilter) ShouldAlert(detection *pipeline.Detection) bool {
-	filterConfig := f.config.GetFilter()
+// resolveSecondsForKey 返回某个策略分组的恢复超时：自定义策略可覆盖，否则回退到全局 FilterConfig.ResolveSeconds
+func (f *AlertFilter) resolveSecondsForKey(sKey string) int {
+	if sKey != defaultStrategyKey {
+		if id, err := strconv.ParseUint(sKey, 10, 64); err == nil {
+			if cs, ok := f.lookupCompiled(uint(id)); ok && cs.strategy.ResolveSeconds > 0 {
+				return cs.strategy.ResolveSeconds
+			}
+		}
+	}
 
-	// 检查是否启用报警推送
-	if !filterConfig.EnableAlertPush {
-		return false
+	seconds := f.config.GetFilter().ResolveSeconds
+	if seconds <= 0 {
+		seconds = defaultResolveSeconds
 	}
+	return seconds
+}
 
-	// 只处理不确定目标
-	if !detection.IsUncertain {
-		return false
+// lookupCompiled 按策略 ID 查找 strategy_cache 中已编译的策略，自定义策略专用（ID 0 恒未找到）
+func (f *AlertFilter) lookupCompiled(id uint) (*compiledStrategy, bool) {
+	if id == 0 {
+		return nil, false
 	}
 
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	f.strategyMu.RLock()
+	defer f.strategyMu.RUnlock()
 
-	// 计算当前检测框的中心点
-	centerX := (detection.X1 + detection.X2) / 2
-	centerY := (detection.Y1 + detection.Y2) / 2
+	cs, ok := f.strategies[id]
+	return cs, ok
+}
 
-	// 清理过期记录
-	expireTime := time.Now().Add(-time.Duration(filterConfig.TimeWindowSeconds) * time.Second)
-	validAlerts := make([]*ActiveAlert, 0, len(f.activeAlerts))
-	for _, alert := range f.activeAlerts {
-		if alert.Timestamp.After(expireTime) {
-			validAlerts = append(validAlerts, alert)
+// detectionFields 把一条检测结果映射为表达式求值可用的字段名 -> 值
+func (f *AlertFilter) detectionFields(detection *pipeline.Detection) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              float64(detection.ID),
+		"confidence":      detection.Confidence,
+		"entropy":         detection.Entropy,
+		"class_name":      detection.ClassName,
+		"class_id":        float64(detection.ClassID),
+		"is_uncertain":    detection.IsUncertain,
+		"x1":              detection.X1,
+		"y1":              detection.Y1,
+		"x2":              detection.X2,
+		"y2":              detection.Y2,
+		"iou_with_active": f.maxActiveIoU(detection),
+	}
+}
+
+// maxActiveIoU 返回该检测框与任意一条当前 pending 或 firing 中的轨迹之间的最大 IoU
+func (f *AlertFilter) maxActiveIoU(detection *pipeline.Detection) float64 {
+	f.trackMu.Lock()
+	defer f.trackMu.Unlock()
+
+	var best float32
+	consider := func(groups map[string]map[string]*ActiveAlert) {
+		for _, alerts := range groups {
+			for _, alert := range alerts {
+				iou := calculateIoU(
+					detection.X1, detection.Y1, detection.X2, detection.Y2,
+					alert.X1, alert.Y1, alert.X2, alert.Y2,
+				)
+				if iou > best {
+					best = iou
+				}
+			}
 		}
 	}
-	f.activeAlerts = validAlerts
+	consider(f.pendings)
+	consider(f.fires)
 
-	// 检查空间重叠
-	for _, alert := range f.activeAlerts {
-		iou := f.calculateIoU(
-			detection.X1, detection.Y1, detection.X2, detection.Y2,
-			alert.X1, alert.Y1, alert.X2, alert.Y2,
-		)
+	return float64(best)
+}
 
-		if iou > filterConfig.SpatialIoUThreshold {
-			log.Printf("[AlertFilter] 抑制报警: IoU=%.3f > %.3f (阈值)", iou, filterConfig.SpatialIoUThreshold)
-			return false
+// ShouldAlert 按优先级评估检测当前是否匹配某条报警策略（纯匹配，不做任何状态变更）：
+// 自定义策略优先于内置默认策略，命中多条时返回优先级最高者；均未命中返回 nil
+func (f *AlertFilter) ShouldAlert(detection *pipeline.Detection) *AlertStrategy {
+	filterConfig := f.config.GetFilter()
+	if !filterConfig.EnableAlertPush {
+		return nil
+	}
+
+	fields := f.detectionFields(detection)
+
+	if strategy := f.matchCustomStrategy(fields); strategy != nil {
+		return strategy
+	}
+
+	if detection.IsUncertain {
+		return defaultAlertStrategy
+	}
+	return nil
+}
+
+// matchCustomStrategy 按优先级从高到低依次评估已启用的自定义策略，返回第一个实际命中的策略
+func (f *AlertFilter) matchCustomStrategy(fields map[string]interface{}) *AlertStrategy {
+	for _, cs := range f.enabledStrategiesByPriority() {
+		matched, err := cs.compiled.Eval(fields)
+		if err != nil {
+			log.Printf("[AlertFilter] 策略 %q 求值失败: %v", cs.strategy.Name, err)
+			continue
+		}
+		if matched {
+			strategy := cs.strategy
+			return &strategy
 		}
 	}
+	return nil
+}
+
+// enabledStrategiesByPriority 返回已启用策略的快照，按优先级从高到低、ID 从小到大排序（保证结果确定）
+func (f *AlertFilter) enabledStrategiesByPriority() []*compiledStrategy {
+	f.strategyMu.RLock()
+	defer f.strategyMu.RUnlock()
+
+	out := make([]*compiledStrategy, 0, len(f.strategies))
+	for _, cs := range f.strategies {
+		if cs.strategy.Enabled {
+			out = append(out, cs)
+		}
+	}
+
+	for i := 1; i < len(out); i++ {
+		for j := i; j > 0; j-- {
+			a, b := out[j-1].strategy, out[j].strategy
+			if a.Priority > b.Priority || (a.Priority == b.Priority && a.ID < b.ID) {
+				break
+			}
+			out[j-1], out[j] = out[j], out[j-1]
+		}
+	}
+
+	return out
+}
+
+// cooldownKey 冷却期按 (策略, 轨迹) 维度隔离：同一策略下不同目标各自独立冷却，
+// 避免一个目标晋升 firing 后把同一策略命中的其他目标也一并抑制
+func cooldownKey(strategyKey, trackKey string) string {
+	return strategyKey + "/" + trackKey
+}
+
+// inCooldown 判断某条轨迹是否仍处于上次晋升 firing 后的冷却期内
+func (f *AlertFilter) inCooldown(key string, cooldownSeconds int) bool {
+	if cooldownSeconds <= 0 {
+		return false
+	}
 
-	// 添加到活跃报警列表
-	f.activeAlerts = append(f.activeAlerts, &ActiveAlert{
-		ID:        detection.ID,
-		CenterX:   centerX,
-		CenterY:   centerY,
-		X1:        detection.X1,
-		Y1:        detection.Y1,
-		X2:        detection.X2,
-		Y2:        detection.Y2,
-		Timestamp: time.Now(),
-	})
+	f.cooldownMu.Lock()
+	defer f.cooldownMu.Unlock()
 
-	log.Printf("[AlertFilter] 新增报警: ID=%d, 位置=(%.1f, %.1f), 活跃报警数=%d",
-		detection.ID, centerX, centerY, len(f.activeAlerts))
+	last, ok := f.cooldowns[key]
+	if !ok {
+		return false
+	}
+	return time.Since(last) < time.Duration(cooldownSeconds)*time.Second
+}
 
-	return true
+// markCooldown 记录某条轨迹晋升为 firing 的时间
+func (f *AlertFilter) markCooldown(key string) {
+	f.cooldownMu.Lock()
+	defer f.cooldownMu.Unlock()
+	f.cooldowns[key] = time.Now()
 }
 
 // calculateIoU 计算两个框的 IoU
-func (f *AlertFilter) calculateIoU(x1a, y1a, x2a, y2a, x1b, y1b, x2b, y2b float32) float32 {
-	// 计算交集
+func calculateIoU(x1a, y1a, x2a, y2a, x1b, y1b, x2b, y2b float32) float32 {
 	x1 := max32(x1a, x1b)
 	y1 := max32(y1a, y1b)
 	x2 := min32(x2a, x2b)
@@ -159,29 +404,42 @@ func (f *AlertFilter) calculateIoU(x1a, y1a, x2a, y2a, x1b, y1b, x2b, y2b float3
 	return intersection / union
 }
 
-// ProcessDetections 处理检测结果，返回需要报警的检测
+// strategyKeyOf 把策略标识转换为 pending/fires 状态机使用的分组键
+func strategyKeyOf(strategy *AlertStrategy) string {
+	if strategy.ID == 0 {
+		return defaultStrategyKey
+	}
+	return strconv.FormatUint(uint64(strategy.ID), 10)
+}
+
+// trackKeyOf 把检测框 ID 转换为轨迹标识，假定同一物理目标的检测框 ID 在追踪期间保持稳定
+func trackKeyOf(detection *pipeline.Detection) string {
+	return strconv.FormatInt(int64(detection.ID), 10)
+}
+
+// ProcessDetections 处理检测结果，推进每条命中轨迹的状态机，返回本次需要推送的 firing 消息
 func (f *AlertFilter) ProcessDetections(result *pipeline.DetectionResult) []*ws.AlertMessage {
+	if f.registry != nil && f.registry.Enabled() {
+		if owner, ok := f.registry.Ring().GetNode(shardKeyOf(result)); ok && owner != f.registry.SelfEndpoint() {
+			err := f.forwarder.PostJSON(owner, forwardDetectionPath, result)
+			if err == nil {
+				return nil
+			}
+			log.Printf("[AlertFilter] 转发检测结果到节点 %s 失败，回退到本地处理: %v", owner, err)
+		}
+	}
+
 	alerts := make([]*ws.AlertMessage, 0)
 
 	for _, detection := range result.Detections {
-		if f.ShouldAlert(detection) {
-			// 创建报警消息
-			alert := &ws.AlertMessage{
-				ID:         detection.ID,
-				FrameID:    result.FrameID,
-				Timestamp:  time.Now().UnixMilli(),
-				X1:         detection.X1,
-				Y1:         detection.Y1,
-				X2:         detection.X2,
-				Y2:         detection.Y2,
-				ClassName:  detection.ClassName,
-				Confidence: detection.Confidence,
-				Entropy:    detection.Entropy,
-			}
+		metrics.RecordReceived(detection.ClassName)
 
-			// TODO: 裁剪图像并编码为 Base64
-			// 这里需要从 result.Frame.Data 中裁剪出扩展后的区域
+		strategy := f.ShouldAlert(detection)
+		if strategy == nil {
+			continue
+		}
 
+		if alert := f.track(strategy, detection, result); alert != nil {
 			alerts = append(alerts, alert)
 		}
 	}
@@ -189,26 +447,258 @@ func (f *AlertFilter) ProcessDetections(result *pipeline.DetectionResult) []*ws.
 	return alerts
 }
 
-// GetActiveAlertsCount 获取活跃报警数量
+// track 推进一条轨迹的 pending -> firing 状态机。已处于 firing 的轨迹只续期不重复推送；
+// 首次命中或仍在 pending 的轨迹更新最新状态，满足 FOR 时长后晋升为 firing 并返回待推送的消息
+func (f *AlertFilter) track(strategy *AlertStrategy, detection *pipeline.Detection, result *pipeline.DetectionResult) *ws.AlertMessage {
+	sKey := strategyKeyOf(strategy)
+	tKey := trackKeyOf(detection)
+	now := time.Now()
+
+	f.trackMu.Lock()
+	defer f.trackMu.Unlock()
+	defer func() { metrics.SetActiveAlerts(f.activeAlertsCountLocked()) }()
+
+	if fires, ok := f.fires[sKey]; ok {
+		if alert, ok := fires[tKey]; ok {
+			alert.LastSeen = now
+			metrics.RecordSuppressedIoU()
+			return nil
+		}
+	}
+
+	if f.inCooldown(cooldownKey(sKey, tKey), strategy.CooldownSeconds) {
+		metrics.RecordSuppressedTime()
+		return nil
+	}
+
+	pendings := f.groupLocked(f.pendings, sKey)
+	pending, exists := pendings[tKey]
+	if !exists {
+		pending = &ActiveAlert{TrackKey: tKey, FirstSeen: now}
+		pendings[tKey] = pending
+	}
+	pending.LastSeen = now
+	pending.X1, pending.Y1, pending.X2, pending.Y2 = detection.X1, detection.Y1, detection.X2, detection.Y2
+	pending.CameraID = result.Frame.CameraID
+	pending.FrameID = result.FrameID
+	pending.ClassName = detection.ClassName
+	pending.Confidence = detection.Confidence
+	pending.Entropy = detection.Entropy
+	pending.StrategyID = strategy.ID
+	pending.Severity = strategy.Severity
+
+	forSeconds := 0
+	if cs, ok := f.lookupCompiled(strategy.ID); ok {
+		forSeconds = cs.compiled.ForSeconds
+	}
+	if now.Sub(pending.FirstSeen) < time.Duration(forSeconds)*time.Second {
+		return nil
+	}
+
+	delete(pendings, tKey)
+	f.groupLocked(f.fires, sKey)[tKey] = pending
+	f.markCooldown(cooldownKey(sKey, tKey))
+
+	log.Printf("[AlertFilter] 轨迹触发: strategy=%d, track=%s, severity=%s", strategy.ID, tKey, strategy.Severity)
+	metrics.RecordFired(pending.ClassName)
+	return f.buildAlertMessage(pending, "firing")
+}
+
+// groupLocked 返回某个策略分组的轨迹表，不存在则创建；调用方需持有 trackMu
+func (f *AlertFilter) groupLocked(groups map[string]map[string]*ActiveAlert, sKey string) map[string]*ActiveAlert {
+	group, ok := groups[sKey]
+	if !ok {
+		group = make(map[string]*ActiveAlert)
+		groups[sKey] = group
+	}
+	return group
+}
+
+// buildAlertMessage 把一条轨迹状态转换为推送给下游的报警消息，并异步分发给已配置的通知渠道
+func (f *AlertFilter) buildAlertMessage(alert *ActiveAlert, eventType string) *ws.AlertMessage {
+	id, _ := strconv.ParseInt(alert.TrackKey, 10, 32)
+	msg := &ws.AlertMessage{
+		ID:         int32(id),
+		CameraID:   alert.CameraID,
+		FrameID:    alert.FrameID,
+		Timestamp:  time.Now().UnixMilli(),
+		X1:         alert.X1,
+		Y1:         alert.Y1,
+		X2:         alert.X2,
+		Y2:         alert.Y2,
+		ClassName:  alert.ClassName,
+		Confidence: alert.Confidence,
+		Entropy:    alert.Entropy,
+		StrategyID: alert.StrategyID,
+		Severity:   alert.Severity,
+		EventType:  eventType,
+	}
+
+	f.dispatcher.Dispatch(msg)
+
+	return msg
+}
+
+// activeAlertsCountLocked 统计活跃轨迹数量（pending + firing，跨所有策略分组），调用方需持有 trackMu
+func (f *AlertFilter) activeAlertsCountLocked() int {
+	count := 0
+	for _, group := range f.pendings {
+		count += len(group)
+	}
+	for _, group := range f.fires {
+		count += len(group)
+	}
+	return count
+}
+
+// GetActiveAlertsCount 获取活跃轨迹数量（pending + firing，跨所有策略分组）
 func (f *AlertFilter) GetActiveAlertsCount() int {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
-	return len(f.activeAlerts)
+	f.trackMu.Lock()
+	defer f.trackMu.Unlock()
+	return f.activeAlertsCountLocked()
 }
 
-// GetStats 获取统计信息
+// GetStats 获取统计信息，报警计数部分直接复用 metrics 包中已注册的 Prometheus 指标快照
 func (f *AlertFilter) GetStats() map[string]interface{} {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
-
 	filterConfig := f.config.GetFilter()
+	snapshot := metrics.Snapshot()
 
 	return map[string]interface{}{
-		"active_alerts_count":   len(f.activeAlerts),
+		"active_alerts_count":   snapshot.ActiveAlerts,
+		"received_total":        snapshot.ReceivedTotal,
+		"suppressed_iou_total":  snapshot.SuppressedIoUTotal,
+		"suppressed_time_total": snapshot.SuppressedTimeTotal,
+		"fired_total":           snapshot.FiredTotal,
+		"resolved_total":        snapshot.ResolvedTotal,
 		"spatial_iou_threshold": filterConfig.SpatialIoUThreshold,
 		"time_window_seconds":   filterConfig.TimeWindowSeconds,
+		"resolve_seconds":       filterConfig.ResolveSeconds,
 		"enable_alert_push":     filterConfig.EnableAlertPush,
+		"strategy_count":        len(f.ListStrategies()),
+	}
+}
+
+// ======================== 自定义报警策略管理 ========================
+
+// AddStrategy 新增一条自定义报警策略并持久化，表达式在注册时编译一次，后续复用编译结果
+func (f *AlertFilter) AddStrategy(strategy AlertStrategy) (AlertStrategy, error) {
+	compiled, err := CompileExpression(strategy.Expression)
+	if err != nil {
+		return AlertStrategy{}, err
 	}
+
+	f.strategyMu.Lock()
+	defer f.strategyMu.Unlock()
+
+	f.nextStrategyID++
+	strategy.ID = f.nextStrategyID
+	strategy.CreatedAt = time.Now()
+
+	f.strategies[strategy.ID] = &compiledStrategy{strategy: strategy, compiled: compiled}
+
+	if err := f.persistStrategiesLocked(); err != nil {
+		log.Printf("[AlertFilter] 持久化报警策略失败: %v", err)
+	}
+
+	return strategy, nil
+}
+
+// RemoveStrategy 删除一条自定义报警策略
+func (f *AlertFilter) RemoveStrategy(id uint) error {
+	f.strategyMu.Lock()
+	defer f.strategyMu.Unlock()
+
+	if _, ok := f.strategies[id]; !ok {
+		return fmt.Errorf("报警策略 %d 不存在", id)
+	}
+
+	delete(f.strategies, id)
+	return f.persistStrategiesLocked()
+}
+
+// ListStrategies 返回当前所有自定义报警策略
+func (f *AlertFilter) ListStrategies() []AlertStrategy {
+	f.strategyMu.RLock()
+	defer f.strategyMu.RUnlock()
+
+	entries := make([]AlertStrategy, 0, len(f.strategies))
+	for _, cs := range f.strategies {
+		entries = append(entries, cs.strategy)
+	}
+	return entries
+}
+
+// syncStrategiesLoop 周期性地从 SystemConfig 重新加载策略，使其它进程/管理界面对策略的修改能够生效，
+// 效果类似 open-falcon Judge 定期从配置中心拉取最新策略（SyncStrategies）
+func (f *AlertFilter) syncStrategiesLoop() {
+	ticker := time.NewTicker(strategySyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case <-ticker.C:
+			if err := f.loadStrategies(); err != nil {
+				log.Printf("[AlertFilter] 刷新 strategy_cache 失败: %v", err)
+			}
+		}
+	}
+}
+
+// loadStrategies 从 SystemConfig 重新加载并编译全部策略，整体替换 strategy_cache
+func (f *AlertFilter) loadStrategies() error {
+	raw, err := model.GetSystemConfig(systemConfigStrategyKey)
+	if err != nil {
+		return err
+	}
+	if raw == "" {
+		return nil
+	}
+
+	var entries []AlertStrategy
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return fmt.Errorf("解析报警策略失败: %w", err)
+	}
+
+	compiledEntries := make(map[uint]*compiledStrategy, len(entries))
+	var maxID uint
+	for _, entry := range entries {
+		compiled, err := CompileExpression(entry.Expression)
+		if err != nil {
+			log.Printf("[AlertFilter] 加载策略 %q 失败，表达式无效: %v", entry.Name, err)
+			continue
+		}
+		compiledEntries[entry.ID] = &compiledStrategy{strategy: entry, compiled: compiled}
+		if entry.ID > maxID {
+			maxID = entry.ID
+		}
+	}
+
+	f.strategyMu.Lock()
+	defer f.strategyMu.Unlock()
+
+	f.strategies = compiledEntries
+	if maxID > f.nextStrategyID {
+		f.nextStrategyID = maxID
+	}
+
+	return nil
+}
+
+// persistStrategiesLocked 将当前 strategy_cache 写回 SystemConfig，调用方需持有 strategyMu
+func (f *AlertFilter) persistStrategiesLocked() error {
+	entries := make([]AlertStrategy, 0, len(f.strategies))
+	for _, cs := range f.strategies {
+		entries = append(entries, cs.strategy)
+	}
+
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	return model.SetSystemConfig(systemConfigStrategyKey, string(raw))
 }
 
 // 辅助函数
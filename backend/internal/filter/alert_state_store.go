@@ -0,0 +1,203 @@
+package filter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"anomaly_detection_system/backend/internal/metrics"
+)
+
+// 状态快照默认值：FilterConfig 未配置对应字段时回退到这里
+const (
+	defaultStateSaveInterval = 30 * time.Second
+	defaultStatePath         = "data/alert_filter_state.gob.gz"
+)
+
+// persistedState 是快照文件里的数据结构，效仿 bosun Schedule.save 的 gzip+gob 编码方式：
+// 重启后据此恢复 pendings/fires，避免对已经看过的目标重新发出一遍报警
+type persistedState struct {
+	SavedAt  time.Time
+	Pendings map[string]map[string]*ActiveAlert
+	Fires    map[string]map[string]*ActiveAlert
+}
+
+// StateSnapshot 是 /api/filter/state 调试接口返回的数据结构
+type StateSnapshot struct {
+	SavedAt  time.Time                          `json:"saved_at"`
+	Pendings map[string]map[string]*ActiveAlert `json:"pendings"`
+	Fires    map[string]map[string]*ActiveAlert `json:"fires"`
+}
+
+// statePath 返回快照文件路径：FilterConfig.StatePath 未配置时回退到默认路径
+func (f *AlertFilter) statePath() string {
+	if path := f.config.GetFilter().StatePath; path != "" {
+		return path
+	}
+	return defaultStatePath
+}
+
+// stateSaveInterval 返回快照落盘周期：FilterConfig.StateSaveIntervalSeconds 非正数时回退到默认值
+func (f *AlertFilter) stateSaveInterval() time.Duration {
+	seconds := f.config.GetFilter().StateSaveIntervalSeconds
+	if seconds <= 0 {
+		return defaultStateSaveInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// saveStateLoop 按 stateSaveInterval 周期性把 pendings/fires 快照落盘，直到 Stop 被调用
+func (f *AlertFilter) saveStateLoop() {
+	ticker := time.NewTicker(f.stateSaveInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case <-ticker.C:
+			if err := f.saveState(); err != nil {
+				log.Printf("[AlertFilter] 保存状态快照失败: %v", err)
+			}
+		}
+	}
+}
+
+// saveState 把当前 pendings/fires 编码为 gzip+gob 并原子写入快照文件（先写临时文件再 rename），
+// 避免进程崩溃时留下写了一半的快照
+func (f *AlertFilter) saveState() error {
+	f.trackMu.Lock()
+	state := persistedState{
+		SavedAt:  time.Now(),
+		Pendings: cloneGroupsLocked(f.pendings),
+		Fires:    cloneGroupsLocked(f.fires),
+	}
+	f.trackMu.Unlock()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if err := gob.NewEncoder(gw).Encode(state); err != nil {
+		return fmt.Errorf("编码状态快照失败: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("压缩状态快照失败: %w", err)
+	}
+
+	path := f.statePath()
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("创建状态快照目录失败: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("写入临时状态快照失败: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// loadState 从快照文件恢复 pendings/fires，按 TimeWindowSeconds 过滤掉消失已久的轨迹；
+// 快照文件不存在视为首次启动，直接返回 nil
+func (f *AlertFilter) loadState() error {
+	raw, err := os.ReadFile(f.statePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取状态快照失败: %w", err)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("解压状态快照失败: %w", err)
+	}
+	defer gr.Close()
+
+	var state persistedState
+	if err := gob.NewDecoder(gr).Decode(&state); err != nil {
+		return fmt.Errorf("解析状态快照失败: %w", err)
+	}
+
+	window := time.Duration(f.config.GetFilter().TimeWindowSeconds) * time.Second
+	if window <= 0 {
+		window = defaultResolveSeconds * time.Second
+	}
+	now := time.Now()
+
+	f.trackMu.Lock()
+	f.pendings = filterStaleGroups(state.Pendings, now, window)
+	f.fires = filterStaleGroups(state.Fires, now, window)
+	restored := f.activeAlertsCountLocked()
+	f.trackMu.Unlock()
+
+	log.Printf("[AlertFilter] 已从状态快照恢复 %d 条轨迹 (快照时间: %s)", restored, state.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+// filterStaleGroups 丢弃消失超过 window 的轨迹，返回可直接赋给 f.pendings/f.fires 的副本
+func filterStaleGroups(groups map[string]map[string]*ActiveAlert, now time.Time, window time.Duration) map[string]map[string]*ActiveAlert {
+	out := make(map[string]map[string]*ActiveAlert, len(groups))
+	for sKey, alerts := range groups {
+		group := make(map[string]*ActiveAlert)
+		for tKey, alert := range alerts {
+			if now.Sub(alert.LastSeen) < window {
+				group[tKey] = alert
+			}
+		}
+		if len(group) > 0 {
+			out[sKey] = group
+		}
+	}
+	return out
+}
+
+// cloneGroupsLocked 深拷贝 pendings/fires 的一份快照供落盘或调试接口使用，避免与在线读写竞争；
+// 调用方需持有 trackMu
+func cloneGroupsLocked(groups map[string]map[string]*ActiveAlert) map[string]map[string]*ActiveAlert {
+	out := make(map[string]map[string]*ActiveAlert, len(groups))
+	for sKey, alerts := range groups {
+		group := make(map[string]*ActiveAlert, len(alerts))
+		for tKey, alert := range alerts {
+			copied := *alert
+			group[tKey] = &copied
+		}
+		out[sKey] = group
+	}
+	return out
+}
+
+// GetStateSnapshot 返回当前 pendings/fires 的快照，供 /api/filter/state 调试接口使用
+func (f *AlertFilter) GetStateSnapshot() StateSnapshot {
+	f.trackMu.Lock()
+	defer f.trackMu.Unlock()
+
+	return StateSnapshot{
+		SavedAt:  time.Now(),
+		Pendings: cloneGroupsLocked(f.pendings),
+		Fires:    cloneGroupsLocked(f.fires),
+	}
+}
+
+// Reset 原子地清空内存中的 pendings/fires/冷却状态，并删除已持久化的状态快照文件
+func (f *AlertFilter) Reset() error {
+	f.trackMu.Lock()
+	f.pendings = make(map[string]map[string]*ActiveAlert)
+	f.fires = make(map[string]map[string]*ActiveAlert)
+	f.trackMu.Unlock()
+	metrics.SetActiveAlerts(0)
+
+	f.cooldownMu.Lock()
+	f.cooldowns = make(map[string]time.Time)
+	f.cooldownMu.Unlock()
+
+	if err := os.Remove(f.statePath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除状态快照失败: %w", err)
+	}
+	return nil
+}
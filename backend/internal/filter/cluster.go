@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"fmt"
+	"log"
+
+	"anomaly_detection_system/backend/internal/metrics"
+	"anomaly_detection_system/backend/internal/naming"
+	"anomaly_detection_system/backend/internal/pipeline"
+)
+
+// HTTP 转发路径：本节点与集群中其他节点都运行同一份二进制，因此两端约定一致
+const (
+	forwardDetectionPath = "/internal/detections/forward" // 转发不归本节点处理的 DetectionResult
+	forwardStatePath     = "/internal/state/import"        // 再平衡时把交接的轨迹状态推给新的所属节点
+
+	defaultShardKey = "default" // Frame.CameraID 为空（单摄像头场景）时使用的分片键
+)
+
+// StateImportPayload 是再平衡时通过 forwardStatePath 推送给新所属节点的一条轨迹状态
+type StateImportPayload struct {
+	Bucket      string       `json:"bucket"` // "pending" 或 "firing"
+	StrategyKey string       `json:"strategy_key"`
+	TrackKey    string       `json:"track_key"`
+	Alert       *ActiveAlert `json:"alert"`
+}
+
+// shardKeyOf 返回一个 DetectionResult 在哈希环上使用的分片键：优先取 Frame.CameraID（即 stream_id），
+// 单摄像头场景下 CameraID 为空，退化为固定的 defaultShardKey
+func shardKeyOf(result *pipeline.DetectionResult) string {
+	if result.Frame != nil && result.Frame.CameraID != "" {
+		return result.Frame.CameraID
+	}
+	return defaultShardKey
+}
+
+func shardKeyForCamera(cameraID string) string {
+	if cameraID == "" {
+		return defaultShardKey
+	}
+	return cameraID
+}
+
+// rebalanceLoop 监听 registry 的成员变化信号，触发一次再平衡，直到 Stop 被调用
+func (f *AlertFilter) rebalanceLoop() {
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case <-f.registry.Changes():
+			f.rebalance()
+		}
+	}
+}
+
+// rebalance 在集群成员发生变化后，把哈希环上已不归本节点负责的轨迹交接给新的所属节点：
+// 先尝试把轨迹状态推送过去，推送成功后再从本地 drop，避免推送失败时丢失正在追踪的轨迹，
+// 从而保证交接前后对同一目标的抑制/冷却状态不被打断
+func (f *AlertFilter) rebalance() {
+	self := f.registry.SelfEndpoint()
+	ring := f.registry.Ring()
+
+	f.trackMu.Lock()
+	f.pendings = f.drainToOwnersLocked(f.pendings, ring, self, "pending")
+	f.fires = f.drainToOwnersLocked(f.fires, ring, self, "firing")
+	metrics.SetActiveAlerts(f.activeAlertsCountLocked())
+	f.trackMu.Unlock()
+}
+
+// drainToOwnersLocked 遍历 groups，把归属权已转移到其他节点的轨迹推送给新所属节点并从本地移除；
+// 调用方需持有 trackMu
+func (f *AlertFilter) drainToOwnersLocked(groups map[string]map[string]*ActiveAlert, ring *naming.HashRing, self, bucket string) map[string]map[string]*ActiveAlert {
+	out := make(map[string]map[string]*ActiveAlert, len(groups))
+	for sKey, alerts := range groups {
+		remaining := make(map[string]*ActiveAlert, len(alerts))
+		for tKey, alert := range alerts {
+			owner, ok := ring.GetNode(shardKeyForCamera(alert.CameraID))
+			if !ok || owner == self {
+				remaining[tKey] = alert
+				continue
+			}
+
+			payload := StateImportPayload{Bucket: bucket, StrategyKey: sKey, TrackKey: tKey, Alert: alert}
+			if err := f.forwarder.PostJSON(owner, forwardStatePath, payload); err != nil {
+				log.Printf("[AlertFilter] 再平衡：交接轨迹 %s/%s 给节点 %s 失败，暂留本地: %v", sKey, tKey, owner, err)
+				remaining[tKey] = alert
+				continue
+			}
+			log.Printf("[AlertFilter] 再平衡：轨迹 %s/%s 已交接给节点 %s", sKey, tKey, owner)
+		}
+		if len(remaining) > 0 {
+			out[sKey] = remaining
+		}
+	}
+	return out
+}
+
+// ImportTrackedAlert 把其他节点再平衡时交接过来的轨迹状态导入本地 pendings/fires，
+// 供 /internal/state/import 接口调用。payload 来自外部请求，校验不通过时拒绝导入，
+// 避免把 nil Alert 存入 pendings/fires 后在 cleanup() 中解引用 panic
+func (f *AlertFilter) ImportTrackedAlert(payload StateImportPayload) error {
+	if payload.Alert == nil {
+		return fmt.Errorf("alert 不能为空")
+	}
+	if payload.StrategyKey == "" || payload.TrackKey == "" {
+		return fmt.Errorf("strategy_key/track_key 不能为空")
+	}
+
+	f.trackMu.Lock()
+	defer f.trackMu.Unlock()
+	defer func() { metrics.SetActiveAlerts(f.activeAlertsCountLocked()) }()
+
+	groups := f.fires
+	if payload.Bucket == "pending" {
+		groups = f.pendings
+	}
+
+	if groups[payload.StrategyKey] == nil {
+		groups[payload.StrategyKey] = make(map[string]*ActiveAlert)
+	}
+	groups[payload.StrategyKey][payload.TrackKey] = payload.Alert
+	return nil
+}
@@ -0,0 +1,488 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprNode 表达式求值节点，策略表达式只在注册/刷新时编译一次，之后复用同一棵树反复求值
+type exprNode interface {
+	eval(fields map[string]interface{}) (bool, error)
+}
+
+// comparisonExpr 单个比较，例如 "entropy > 0.7" 或 "class_name == \"person\""
+type comparisonExpr struct {
+	field string
+	op    string
+	value interface{} // float64 | string | bool
+}
+
+func (e *comparisonExpr) eval(fields map[string]interface{}) (bool, error) {
+	actual, ok := fields[e.field]
+	if !ok {
+		return false, fmt.Errorf("未知字段: %s", e.field)
+	}
+
+	switch av := actual.(type) {
+	case float32:
+		return compareNumber(float64(av), e.op, e.value)
+	case float64:
+		return compareNumber(av, e.op, e.value)
+	case bool:
+		return compareBool(av, e.op, e.value)
+	case string:
+		return compareString(av, e.op, e.value)
+	default:
+		return false, fmt.Errorf("字段 %s 的类型不支持比较", e.field)
+	}
+}
+
+func compareNumber(actual float64, op string, value interface{}) (bool, error) {
+	expected, ok := value.(float64)
+	if !ok {
+		return false, fmt.Errorf("数值字段需要与数值比较")
+	}
+	switch op {
+	case ">":
+		return actual > expected, nil
+	case "<":
+		return actual < expected, nil
+	case ">=":
+		return actual >= expected, nil
+	case "<=":
+		return actual <= expected, nil
+	case "==":
+		return actual == expected, nil
+	case "!=":
+		return actual != expected, nil
+	}
+	return false, fmt.Errorf("数值字段不支持运算符: %s", op)
+}
+
+func compareBool(actual bool, op string, value interface{}) (bool, error) {
+	expected, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("布尔字段需要与布尔值比较")
+	}
+	switch op {
+	case "==":
+		return actual == expected, nil
+	case "!=":
+		return actual != expected, nil
+	}
+	return false, fmt.Errorf("布尔字段只支持 == / !=，收到: %s", op)
+}
+
+func compareString(actual string, op string, value interface{}) (bool, error) {
+	expected, ok := value.(string)
+	if !ok {
+		return false, fmt.Errorf("字符串字段需要与字符串比较")
+	}
+	switch op {
+	case "==":
+		return actual == expected, nil
+	case "!=":
+		return actual != expected, nil
+	}
+	return false, fmt.Errorf("字符串字段只支持 == / !=，收到: %s", op)
+}
+
+// inExpr 形如 "class_name IN (\"person\", \"vehicle\")"
+type inExpr struct {
+	field  string
+	values []string
+}
+
+func (e *inExpr) eval(fields map[string]interface{}) (bool, error) {
+	actual, ok := fields[e.field]
+	if !ok {
+		return false, fmt.Errorf("未知字段: %s", e.field)
+	}
+	str, ok := actual.(string)
+	if !ok {
+		return false, fmt.Errorf("字段 %s 不是字符串，无法使用 IN", e.field)
+	}
+	for _, v := range e.values {
+		if v == str {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// logicalExpr 形如 "a AND b" / "a OR b"，均为短路求值
+type logicalExpr struct {
+	op    string // "AND" | "OR"
+	left  exprNode
+	right exprNode
+}
+
+func (e *logicalExpr) eval(fields map[string]interface{}) (bool, error) {
+	left, err := e.left.eval(fields)
+	if err != nil {
+		return false, err
+	}
+	if e.op == "AND" {
+		if !left {
+			return false, nil
+		}
+		return e.right.eval(fields)
+	}
+	if left {
+		return true, nil
+	}
+	return e.right.eval(fields)
+}
+
+// CompiledExpression 编译后的策略表达式。ForSeconds 来自表达式末尾可选的 "FOR <N>s" 子句，
+// 表示该条件需要连续满足多久才算命中，<=0 表示立即命中（由 AlertFilter 负责计时与判定）
+type CompiledExpression struct {
+	root       exprNode
+	ForSeconds int
+}
+
+// Eval 对外暴露的字段名 -> 值求值入口
+func (c *CompiledExpression) Eval(fields map[string]interface{}) (bool, error) {
+	if c.root == nil {
+		return false, nil
+	}
+	return c.root.eval(fields)
+}
+
+// CompileExpression 编译策略表达式。支持的运算符: > < >= <= == != AND OR IN，
+// 标识符为 pipeline.Detection 的字段名（见 AlertFilter.detectionFields）
+func CompileExpression(expr string) (*CompiledExpression, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return nil, fmt.Errorf("解析表达式 %q 失败: %w", expr, err)
+	}
+
+	tokens, forSeconds, err := extractForClause(tokens)
+	if err != nil {
+		return nil, fmt.Errorf("解析表达式 %q 的 FOR 子句失败: %w", expr, err)
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("解析表达式 %q 失败: %w", expr, err)
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("表达式 %q 存在无法解析的尾部", expr)
+	}
+
+	return &CompiledExpression{root: root, ForSeconds: forSeconds}, nil
+}
+
+// ======================== 词法分析 ========================
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokNumber
+	tokString
+	tokDuration // 形如 "3s" 的时长字面量，仅出现在 FOR 子句中
+	tokOp       // > < >= <= == !=
+	tokAnd
+	tokOr
+	tokIn
+	tokFor
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	num  float64
+	dur  int
+}
+
+func tokenizeExpression(expr string) ([]exprToken, error) {
+	runes := []rune(expr)
+	n := len(runes)
+	var tokens []exprToken
+
+	i := 0
+	for i < n {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			tokens = append(tokens, exprToken{kind: tokLParen})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, exprToken{kind: tokRParen})
+			i++
+
+		case c == ',':
+			tokens = append(tokens, exprToken{kind: tokComma})
+			i++
+
+		case c == '"' || c == '\'':
+			quote := c
+			j := i + 1
+			for j < n && runes[j] != quote {
+				j++
+			}
+			if j >= n {
+				return nil, fmt.Errorf("字符串字面量未闭合")
+			}
+			tokens = append(tokens, exprToken{kind: tokString, text: string(runes[i+1 : j])})
+			i = j + 1
+
+		case c == '>' || c == '<' || c == '=' || c == '!':
+			op := string(c)
+			j := i + 1
+			if j < n && runes[j] == '=' {
+				op += "="
+				j++
+			}
+			if op == "=" {
+				return nil, fmt.Errorf("不支持的运算符 '='，请使用 '=='")
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("不支持的运算符 '!'，请使用 '!='")
+			}
+			tokens = append(tokens, exprToken{kind: tokOp, text: op})
+			i = j
+
+		case unicode.IsDigit(c) || (c == '-' && i+1 < n && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			if c == '-' {
+				j = i + 1
+			} else {
+				j = i
+			}
+			for j < n && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			numText := string(runes[i:j])
+
+			// 紧跟一个 's' 且不再续接字母/数字，视为 FOR 子句中的秒数时长字面量
+			if j < n && runes[j] == 's' && (j+1 >= n || !isIdentRune(runes[j+1])) {
+				seconds, convErr := strconv.Atoi(numText)
+				if convErr != nil {
+					return nil, fmt.Errorf("非法的时长字面量: %ss", numText)
+				}
+				tokens = append(tokens, exprToken{kind: tokDuration, dur: seconds})
+				i = j + 1
+				continue
+			}
+
+			val, convErr := strconv.ParseFloat(numText, 64)
+			if convErr != nil {
+				return nil, fmt.Errorf("非法的数值字面量: %s", numText)
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, num: val})
+			i = j
+
+		case isIdentStart(c):
+			j := i
+			for j < n && isIdentRune(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			switch strings.ToUpper(word) {
+			case "AND":
+				tokens = append(tokens, exprToken{kind: tokAnd})
+			case "OR":
+				tokens = append(tokens, exprToken{kind: tokOr})
+			case "IN":
+				tokens = append(tokens, exprToken{kind: tokIn})
+			case "FOR":
+				tokens = append(tokens, exprToken{kind: tokFor})
+			default:
+				tokens = append(tokens, exprToken{kind: tokIdent, text: strings.ToLower(word)})
+			}
+			i = j
+
+		default:
+			return nil, fmt.Errorf("表达式中存在非法字符: %q", c)
+		}
+	}
+
+	return tokens, nil
+}
+
+func isIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
+
+func isIdentRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// extractForClause 若 tokens 以 "FOR <duration>" 结尾则剥离并返回其秒数，否则 forSeconds 为 0
+func extractForClause(tokens []exprToken) ([]exprToken, int, error) {
+	if len(tokens) < 2 {
+		return tokens, 0, nil
+	}
+	last := tokens[len(tokens)-1]
+	secondLast := tokens[len(tokens)-2]
+	if secondLast.kind != tokFor {
+		return tokens, 0, nil
+	}
+	if last.kind != tokDuration {
+		return nil, 0, fmt.Errorf("FOR 子句后必须是时长字面量，例如 FOR 3s")
+	}
+	return tokens[:len(tokens)-2], last.dur, nil
+}
+
+// ======================== 语法分析（递归下降） ========================
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() (exprToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return exprToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *exprParser) next() (exprToken, bool) {
+	t, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+	return t, ok
+}
+
+// parseOr := parseAnd (OR parseAnd)*
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOr {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "OR", left: left, right: right}
+	}
+}
+
+// parseAnd := parsePrimary (AND parsePrimary)*
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokAnd {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "AND", left: left, right: right}
+	}
+}
+
+// parsePrimary := "(" parseOr ")" | comparison
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if t, ok := p.peek(); ok && t.kind == tokLParen {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.next()
+		if !ok || closing.kind != tokRParen {
+			return nil, fmt.Errorf("缺少匹配的右括号")
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison := IDENT (OP value | IN "(" STRING ("," STRING)* ")")
+func (p *exprParser) parseComparison() (exprNode, error) {
+	identTok, ok := p.next()
+	if !ok || identTok.kind != tokIdent {
+		return nil, fmt.Errorf("期望字段名")
+	}
+	field := identTok.text
+
+	opTok, ok := p.next()
+	if !ok {
+		return nil, fmt.Errorf("字段 %s 后缺少运算符", field)
+	}
+
+	if opTok.kind == tokIn {
+		lparen, ok := p.next()
+		if !ok || lparen.kind != tokLParen {
+			return nil, fmt.Errorf("IN 后缺少 '('")
+		}
+
+		var values []string
+		for {
+			v, ok := p.next()
+			if !ok || v.kind != tokString {
+				return nil, fmt.Errorf("IN 列表只支持字符串字面量")
+			}
+			values = append(values, v.text)
+
+			sep, ok := p.next()
+			if !ok {
+				return nil, fmt.Errorf("IN 列表缺少右括号")
+			}
+			if sep.kind == tokRParen {
+				break
+			}
+			if sep.kind != tokComma {
+				return nil, fmt.Errorf("IN 列表中存在非法分隔符")
+			}
+		}
+		return &inExpr{field: field, values: values}, nil
+	}
+
+	if opTok.kind != tokOp {
+		return nil, fmt.Errorf("字段 %s 后期望比较运算符", field)
+	}
+
+	valTok, ok := p.next()
+	if !ok {
+		return nil, fmt.Errorf("运算符 %s 后缺少比较值", opTok.text)
+	}
+
+	var value interface{}
+	switch valTok.kind {
+	case tokNumber:
+		value = valTok.num
+	case tokString:
+		value = valTok.text
+	case tokIdent:
+		switch valTok.text {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		default:
+			return nil, fmt.Errorf("不支持的比较值: %s", valTok.text)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的比较值类型")
+	}
+
+	return &comparisonExpr{field: field, op: opTok.text, value: value}, nil
+}
@@ -3,31 +3,42 @@ package handler
 import (
 	"log"
 	"net/http"
-	"os/exec"
+	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/filter"
 	"anomaly_detection_system/backend/internal/model"
 	"anomaly_detection_system/backend/internal/pipeline"
+	"anomaly_detection_system/backend/internal/training"
 	pb "anomaly_detection_system/backend/pb"
 )
 
 // Handler HTTP 请求处理器
 type Handler struct {
-	config       *config.Config
-	grpcClient   *pipeline.GRPCClient
-	videoCapture *pipeline.VideoCapture
+	config         *config.Config
+	backend        pipeline.DetectionBackend
+	videoCapture   pipeline.CaptureBackend
+	captureManager *pipeline.CaptureManager
+	scheduler      *training.Scheduler
+	resultFanout   *pipeline.ResultFanout
+	alertFilter    *filter.AlertFilter
 }
 
 // NewHandler 创建处理器
-func NewHandler(cfg *config.Config, grpcClient *pipeline.GRPCClient, videoCapture *pipeline.VideoCapture) *Handler {
+func NewHandler(cfg *config.Config, backend pipeline.DetectionBackend, videoCapture pipeline.CaptureBackend, captureManager *pipeline.CaptureManager, scheduler *training.Scheduler, resultFanout *pipeline.ResultFanout, alertFilter *filter.AlertFilter) *Handler {
 	return &Handler{
-		config:       cfg,
-		grpcClient:   grpcClient,
-		videoCapture: videoCapture,
+		config:         cfg,
+		backend:        backend,
+		videoCapture:   videoCapture,
+		captureManager: captureManager,
+		scheduler:      scheduler,
+		resultFanout:   resultFanout,
+		alertFilter:    alertFilter,
 	}
 }
 
@@ -36,9 +47,17 @@ func NewHandler(cfg *config.Config, grpcClient *pipeline.GRPCClient, videoCaptur
 // VideoConfigRequest 视频配置请求
 type VideoConfigRequest struct {
 	SourceType string `json:"source_type"` // "rtsp" 或 "local"
+	Backend    string `json:"backend"`     // 采集后端: "ffmpeg" | "gocv" | "gortsplib2"，为空时默认 "ffmpeg"
 	RTSPUrl    string `json:"rtsp_url"`    // RTSP 地址
+	Username   string `json:"username"`    // RTSP 认证用户名
+	Password   string `json:"password"`    // RTSP 认证密码
+	Transport  string `json:"transport"`   // RTSP 传输模式: "tcp" | "udp" | "auto"
 	LocalPath  string `json:"local_path"`  // 本地文件路径
 	FPS        int    `json:"fps"`         // 帧率 (30 或 60)
+
+	ReconnectBackoffMs int `json:"reconnect_backoff_ms"` // 重连退避基数（毫秒）
+	ReadTimeoutMs      int `json:"read_timeout_ms"`      // 建连/读取超时（毫秒）
+	StallDetectionMs   int `json:"stall_detection_ms"`   // 无帧判定卡死的时长（毫秒），<=0 表示不启用
 }
 
 // UpdateVideoConfig 更新视频配置
@@ -64,13 +83,28 @@ func (h *Handler) UpdateVideoConfig(c *gin.Context) {
 		return
 	}
 
-	// 更新配置
-	h.config.UpdateVideo(config.VideoConfig{
-		SourceType: req.SourceType,
-		RTSPUrl:    req.RTSPUrl,
-		LocalPath:  req.LocalPath,
-		FPS:        req.FPS,
-	})
+	if req.SourceType == "rtsp" {
+		if err := pipeline.ValidateRTSPURL(req.RTSPUrl); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rtsp_url 非法: " + err.Error()})
+			return
+		}
+	}
+
+	// 更新配置：UpdateVideo 是整体替换（config.go），从现有配置出发只覆盖请求中带的字段，
+	// 避免把 CameraID/InputMode/IdleFPS/IdleWindowSeconds/MotionIoUThreshold 等请求里没有的字段清零
+	videoConfig := h.config.GetVideo()
+	videoConfig.SourceType = req.SourceType
+	videoConfig.Backend = req.Backend
+	videoConfig.RTSPUrl = req.RTSPUrl
+	videoConfig.Username = req.Username
+	videoConfig.Password = req.Password
+	videoConfig.Transport = req.Transport
+	videoConfig.LocalPath = req.LocalPath
+	videoConfig.FPS = req.FPS
+	videoConfig.ReconnectBackoffMs = req.ReconnectBackoffMs
+	videoConfig.ReadTimeoutMs = req.ReadTimeoutMs
+	videoConfig.StallDetectionMs = req.StallDetectionMs
+	h.config.UpdateVideo(videoConfig)
 
 	// 重启视频采集
 	if h.videoCapture != nil {
@@ -93,6 +127,144 @@ func (h *Handler) GetVideoConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, h.config.GetVideo())
 }
 
+// ProbeVideoSource 探测视频源（不下发配置、不重启采集），用于保存前先验证源是否可达
+func (h *Handler) ProbeVideoSource(c *gin.Context) {
+	videoConfig := h.config.GetVideo()
+
+	if sourceType := c.Query("source_type"); sourceType != "" {
+		videoConfig.SourceType = sourceType
+	}
+	if rtspURL := c.Query("rtsp_url"); rtspURL != "" {
+		videoConfig.RTSPUrl = rtspURL
+	}
+	if localPath := c.Query("local_path"); localPath != "" {
+		videoConfig.LocalPath = localPath
+	}
+	if transport := c.Query("transport"); transport != "" {
+		videoConfig.Transport = transport
+	}
+
+	if videoConfig.SourceType == "rtsp" {
+		if err := pipeline.ValidateRTSPURL(videoConfig.RTSPUrl); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rtsp_url 非法: " + err.Error()})
+			return
+		}
+	}
+
+	result, err := pipeline.ProbeSource(videoConfig)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"reachable": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reachable": true, "probe": result})
+}
+
+// ======================== 多摄像头 API ========================
+
+// CameraRequest 新增摄像头请求
+type CameraRequest struct {
+	CameraID   string `json:"camera_id" binding:"required"` // 摄像头唯一标识
+	SourceType string `json:"source_type"`                  // "rtsp" 或 "local"
+	Backend    string `json:"backend"`                      // 采集后端: "ffmpeg" | "gocv" | "gortsplib2"
+	RTSPUrl    string `json:"rtsp_url"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Transport  string `json:"transport"`
+	LocalPath  string `json:"local_path"`
+	FPS        int    `json:"fps"`
+
+	ReconnectBackoffMs int `json:"reconnect_backoff_ms"`
+	ReadTimeoutMs      int `json:"read_timeout_ms"`
+	StallDetectionMs   int `json:"stall_detection_ms"`
+}
+
+// GetCameras 获取所有摄像头配置
+func (h *Handler) GetCameras(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"cameras": h.config.GetCameras()})
+}
+
+// AddCamera 新增一路摄像头并启动对应的采集管线
+func (h *Handler) AddCamera(c *gin.Context) {
+	if h.captureManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "多摄像头管理器未启用"})
+		return
+	}
+
+	var req CameraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误: " + err.Error()})
+		return
+	}
+
+	if req.SourceType != "rtsp" && req.SourceType != "local" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source_type 必须是 'rtsp' 或 'local'"})
+		return
+	}
+	if req.SourceType == "rtsp" {
+		if err := pipeline.ValidateRTSPURL(req.RTSPUrl); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rtsp_url 非法: " + err.Error()})
+			return
+		}
+	}
+
+	cfg := config.VideoConfig{
+		CameraID:           req.CameraID,
+		SourceType:         req.SourceType,
+		Backend:            req.Backend,
+		RTSPUrl:            req.RTSPUrl,
+		Username:           req.Username,
+		Password:           req.Password,
+		Transport:          req.Transport,
+		LocalPath:          req.LocalPath,
+		FPS:                req.FPS,
+		ReconnectBackoffMs: req.ReconnectBackoffMs,
+		ReadTimeoutMs:      req.ReadTimeoutMs,
+		StallDetectionMs:   req.StallDetectionMs,
+	}
+
+	if err := h.captureManager.AddCamera(cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[Handler] 新增摄像头: id=%s, type=%s", req.CameraID, req.SourceType)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "摄像头已添加"})
+}
+
+// RemoveCamera 停止并删除一路摄像头
+func (h *Handler) RemoveCamera(c *gin.Context) {
+	if h.captureManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "多摄像头管理器未启用"})
+		return
+	}
+
+	cameraID := c.Param("id")
+	if err := h.captureManager.RemoveCamera(cameraID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[Handler] 摄像头已删除: id=%s", cameraID)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "摄像头已删除"})
+}
+
+// RestartCamera 重启指定摄像头的采集
+func (h *Handler) RestartCamera(c *gin.Context) {
+	if h.captureManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "多摄像头管理器未启用"})
+		return
+	}
+
+	cameraID := c.Param("id")
+	if err := h.captureManager.RestartCamera(cameraID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "摄像头重启失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "摄像头已重启"})
+}
+
 // ======================== AI 配置 API ========================
 
 // AIConfigRequest AI 配置请求
@@ -158,8 +330,8 @@ func (h *Handler) UpdateAIConfig(c *gin.Context) {
 
 	// 转发到 Python AI 服务
 	aiServiceMessage := ""
-	if h.grpcClient != nil {
-		resp, err := h.grpcClient.UpdateAIParams(grpcReq)
+	if h.backend != nil {
+		resp, err := h.backend.UpdateParams(grpcReq)
 		if err != nil {
 			// AI 服务不可用，仅更新本地配置，不报错
 			log.Printf("[Handler] AI 服务不可用，仅更新本地配置: %v", err)
@@ -344,60 +516,179 @@ func (h *Handler) GetTrainingStatus(c *gin.Context) {
 }
 
 // TriggerTraining 手动触发训练
+//
+// 实际的训练执行、日志落盘与模型重载均由 training.Scheduler 的单一 worker 协程串行处理，
+// 这里只负责把一次手动触发投递到调度器的任务队列。
 func (h *Handler) TriggerTraining(c *gin.Context) {
-	trainingConfig := h.config.GetTraining()
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "训练调度器未启用"})
+		return
+	}
 
-	// 创建训练日志
-	trainingLog := &model.TrainingLog{
-		StartTime: time.Now(),
-		Status:    "running",
+	h.scheduler.TriggerManual()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "训练已加入任务队列",
+	})
+}
+
+// CancelTraining 取消正在运行的训练任务
+func (h *Handler) CancelTraining(c *gin.Context) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "训练调度器未启用"})
+		return
 	}
-	err := model.CreateTrainingLog(trainingLog)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建训练日志失败: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "非法的训练任务 ID"})
 		return
 	}
 
-	// 异步执行训练
-	go func() {
-		log.Printf("[Handler] 开始执行训练脚本: %s", trainingConfig.TrainingScriptPath)
+	if err := h.scheduler.CancelTraining(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-		cmd := exec.Command("python", trainingConfig.TrainingScriptPath)
-		output, err := cmd.CombinedOutput()
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "取消指令已发送"})
+}
 
-		now := time.Now()
-		if err != nil {
-			log.Printf("[Handler] 训练失败: %v, 输出: %s", err, string(output))
-			model.UpdateTrainingLog(trainingLog.ID, map[string]interface{}{
-				"status":        "failed",
-				"end_time":      now,
-				"error_message": err.Error(),
-			})
-			return
-		}
+// ======================== 训练定时计划 API ========================
 
-		log.Printf("[Handler] 训练完成，输出: %s", string(output))
-		model.UpdateTrainingLog(trainingLog.ID, map[string]interface{}{
-			"status":   "completed",
-			"end_time": now,
-		})
-
-		// 触发模型重载
-		if h.grpcClient != nil {
-			resp, err := h.grpcClient.ReloadModel("")
-			if err != nil {
-				log.Printf("[Handler] 模型重载失败: %v", err)
-			} else if resp != nil {
-				log.Printf("[Handler] 模型重载成功: %s", resp.Message)
-			}
-		}
-	}()
+// ScheduleRequest 新增定时训练计划请求
+type ScheduleRequest struct {
+	CronExpr string `json:"cron_expr" binding:"required"`
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":     true,
-		"message":     "训练已启动",
-		"training_id": trainingLog.ID,
+// AddTrainingSchedule 新增一条 cron 定时训练计划
+func (h *Handler) AddTrainingSchedule(c *gin.Context) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "训练调度器未启用"})
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.scheduler.AddSchedule(req.CronExpr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[Handler] 新增定时训练计划: id=%d, cron=%s", entry.ID, entry.CronExpr)
+	c.JSON(http.StatusOK, gin.H{"success": true, "schedule": entry})
+}
+
+// GetTrainingSchedules 获取所有定时训练计划
+func (h *Handler) GetTrainingSchedules(c *gin.Context) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "训练调度器未启用"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": h.scheduler.ListSchedules()})
+}
+
+// DeleteTrainingSchedule 删除一条定时训练计划
+func (h *Handler) DeleteTrainingSchedule(c *gin.Context) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "训练调度器未启用"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "非法的定时计划 ID"})
+		return
+	}
+
+	if err := h.scheduler.RemoveSchedule(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "定时计划已删除"})
+}
+
+// ======================== 报警策略 API ========================
+
+// StrategyRequest 新增报警策略请求
+type StrategyRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Expression      string `json:"expression" binding:"required"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+	ResolveSeconds  int    `json:"resolve_seconds"`
+	Priority        int    `json:"priority"`
+	Severity        string `json:"severity"`
+	NotifyChannel   string `json:"notify_channel"`
+	Enabled         bool   `json:"enabled"`
+}
+
+// AddAlertStrategy 新增一条自定义报警策略
+func (h *Handler) AddAlertStrategy(c *gin.Context) {
+	var req StrategyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	strategy, err := h.alertFilter.AddStrategy(filter.AlertStrategy{
+		Name:            req.Name,
+		Expression:      req.Expression,
+		CooldownSeconds: req.CooldownSeconds,
+		ResolveSeconds:  req.ResolveSeconds,
+		Priority:        req.Priority,
+		Severity:        req.Severity,
+		NotifyChannel:   req.NotifyChannel,
+		Enabled:         req.Enabled,
 	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[Handler] 新增报警策略: id=%d, name=%s", strategy.ID, strategy.Name)
+	c.JSON(http.StatusOK, gin.H{"success": true, "strategy": strategy})
+}
+
+// GetAlertStrategies 获取所有自定义报警策略
+func (h *Handler) GetAlertStrategies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"strategies": h.alertFilter.ListStrategies()})
+}
+
+// DeleteAlertStrategy 删除一条自定义报警策略
+func (h *Handler) DeleteAlertStrategy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "非法的策略 ID"})
+		return
+	}
+
+	if err := h.alertFilter.RemoveStrategy(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "报警策略已删除"})
+}
+
+// GetFilterState 获取当前 pendings/fires 的状态快照，用于调试报警抑制/去重逻辑
+func (h *Handler) GetFilterState(c *gin.Context) {
+	c.JSON(http.StatusOK, h.alertFilter.GetStateSnapshot())
+}
+
+// ResetFilterState 清空内存中的报警去重状态并删除已持久化的快照文件
+func (h *Handler) ResetFilterState(c *gin.Context) {
+	if err := h.alertFilter.Reset(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "报警过滤状态已重置"})
 }
 
 // ======================== 系统状态 API ========================
@@ -405,11 +696,12 @@ func (h *Handler) TriggerTraining(c *gin.Context) {
 // GetSystemStatus 获取系统状态
 func (h *Handler) GetSystemStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"video":    h.config.GetVideo(),
-		"ai":       h.config.GetAI(),
-		"filter":   h.config.GetFilter(),
-		"training": h.config.GetTraining(),
-		"time":     time.Now().Format(time.RFC3339),
+		"video":         h.config.GetVideo(),
+		"ai":            h.config.GetAI(),
+		"filter":        h.config.GetFilter(),
+		"training":      h.config.GetTraining(),
+		"result_fanout": h.resultFanout.Stats(),
+		"time":          time.Now().Format(time.RFC3339),
 	})
 }
 
@@ -423,6 +715,60 @@ func (h *Handler) GetAllConfig(c *gin.Context) {
 	})
 }
 
+// ======================== 报警录像片段 API ========================
+
+// GetClips 分页获取报警录像片段列表
+func (h *Handler) GetClips(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "20")
+	limit, _ := strconv.Atoi(limitStr)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offsetStr := c.DefaultQuery("offset", "0")
+	offset, _ := strconv.Atoi(offsetStr)
+	if offset < 0 {
+		offset = 0
+	}
+
+	clips, total, err := model.GetClips(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clips":  clips,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetClip 以支持 HTTP Range 的方式流式返回单个录像片段的 MP4 文件，供前端拖拽进度条时按需加载
+func (h *Handler) GetClip(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "非法的录像片段 ID"})
+		return
+	}
+
+	clip, err := model.GetClipByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "录像片段不存在"})
+		return
+	}
+
+	file, err := os.Open(clip.FilePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "录像文件不存在: " + err.Error()})
+		return
+	}
+	defer file.Close()
+
+	http.ServeContent(c.Writer, c.Request, filepath.Base(clip.FilePath), clip.CreatedAt, file)
+}
+
 // ======================== 样本列表 API ========================
 
 // GetPendingSamples 获取待处理样本
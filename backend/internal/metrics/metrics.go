@@ -0,0 +1,158 @@
+// Package metrics 提供报警过滤管线的自监控指标：仿 open-falcon transfer 的 proc 包，
+// 每个处理阶段维护一个 Prometheus 指标，同时保留一份原子计数快照供 AlertFilter.GetStats()
+// 和周期性日志摘要复用，不依赖 Prometheus 内部存储。
+package metrics
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	alertsReceivedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "alerts_received_total",
+		Help: "按类别统计的进入报警评估的检测框总数",
+	}, []string{"class_name"})
+
+	alertsSuppressedIoUTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "alerts_suppressed_iou_total",
+		Help: "因已属于某条 firing 中的轨迹（视为同一目标的重复检测）而被抑制的次数",
+	})
+
+	alertsSuppressedTimeTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "alerts_suppressed_time_total",
+		Help: "因命中策略仍处于冷却期而被抑制的次数",
+	})
+
+	alertsFiredTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "alerts_fired_total",
+		Help: "按类别统计晋升为 firing 的轨迹数",
+	}, []string{"class_name"})
+
+	alertsResolvedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "alerts_resolved_total",
+		Help: "按类别统计恢复为 resolved 的轨迹数",
+	}, []string{"class_name"})
+
+	activeAlertsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "active_alerts_gauge",
+		Help: "当前 pending + firing 状态的轨迹数",
+	})
+
+	cleanupDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "cleanup_duration_seconds",
+		Help:    "AlertFilter 单次巡检（cleanup）耗时",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	notifySendTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notify_send_total",
+		Help: "按渠道/结果统计的通知投递次数",
+	}, []string{"channel", "status"})
+)
+
+// counters 是上面各 Prometheus 指标的原子计数镜像，Snapshot()/LogQPSLoop 只读这里，
+// 避免每次都要遍历 Prometheus 的内部 metric family
+var counters struct {
+	received       int64
+	suppressedIoU  int64
+	suppressedTime int64
+	fired          int64
+	resolved       int64
+	activeAlerts   int64
+}
+
+// RecordReceived 记录一次检测框进入报警评估
+func RecordReceived(className string) {
+	alertsReceivedTotal.WithLabelValues(className).Inc()
+	atomic.AddInt64(&counters.received, 1)
+}
+
+// RecordSuppressedIoU 记录一次因已属于某条 firing 轨迹而被抑制
+func RecordSuppressedIoU() {
+	alertsSuppressedIoUTotal.Inc()
+	atomic.AddInt64(&counters.suppressedIoU, 1)
+}
+
+// RecordSuppressedTime 记录一次因策略仍处于冷却期而被抑制
+func RecordSuppressedTime() {
+	alertsSuppressedTimeTotal.Inc()
+	atomic.AddInt64(&counters.suppressedTime, 1)
+}
+
+// RecordFired 记录一条轨迹晋升为 firing
+func RecordFired(className string) {
+	alertsFiredTotal.WithLabelValues(className).Inc()
+	atomic.AddInt64(&counters.fired, 1)
+}
+
+// RecordResolved 记录一条轨迹恢复为 resolved
+func RecordResolved(className string) {
+	alertsResolvedTotal.WithLabelValues(className).Inc()
+	atomic.AddInt64(&counters.resolved, 1)
+}
+
+// SetActiveAlerts 更新当前活跃（pending + firing）轨迹数
+func SetActiveAlerts(count int) {
+	activeAlertsGauge.Set(float64(count))
+	atomic.StoreInt64(&counters.activeAlerts, int64(count))
+}
+
+// ObserveCleanupDuration 记录一次 cleanup 巡检耗时
+func ObserveCleanupDuration(d time.Duration) {
+	cleanupDurationSeconds.Observe(d.Seconds())
+}
+
+// RecordNotifySend 记录一次通知投递结果，status 为 "success" 或 "failed"
+func RecordNotifySend(channel, status string) {
+	notifySendTotal.WithLabelValues(channel, status).Inc()
+}
+
+// Stats 是 Snapshot() 返回的聚合计数，供 AlertFilter.GetStats() 直接复用
+type Stats struct {
+	ReceivedTotal       int64 `json:"received_total"`
+	SuppressedIoUTotal  int64 `json:"suppressed_iou_total"`
+	SuppressedTimeTotal int64 `json:"suppressed_time_total"`
+	FiredTotal          int64 `json:"fired_total"`
+	ResolvedTotal       int64 `json:"resolved_total"`
+	ActiveAlerts        int64 `json:"active_alerts"`
+}
+
+// Snapshot 返回当前计数快照
+func Snapshot() Stats {
+	return Stats{
+		ReceivedTotal:       atomic.LoadInt64(&counters.received),
+		SuppressedIoUTotal:  atomic.LoadInt64(&counters.suppressedIoU),
+		SuppressedTimeTotal: atomic.LoadInt64(&counters.suppressedTime),
+		FiredTotal:          atomic.LoadInt64(&counters.fired),
+		ResolvedTotal:       atomic.LoadInt64(&counters.resolved),
+		ActiveAlerts:        atomic.LoadInt64(&counters.activeAlerts),
+	}
+}
+
+// qpsLogInterval 与 Prometheus 抓取周期无关，只是给没有接入 Prometheus 的运维提供的兜底可见性
+const qpsLogInterval = 60 * time.Second
+
+// LogQPSLoop 每 qpsLogInterval 打印一次 received/fired/resolved 的 QPS 摘要，直到进程退出
+func LogQPSLoop() {
+	ticker := time.NewTicker(qpsLogInterval)
+	defer ticker.Stop()
+
+	var lastReceived, lastFired, lastResolved int64
+	for range ticker.C {
+		s := Snapshot()
+		seconds := qpsLogInterval.Seconds()
+		log.Printf("[Metrics] 过去 %.0fs: received=%.2f/s, fired=%.2f/s, resolved=%.2f/s, active=%d",
+			seconds,
+			float64(s.ReceivedTotal-lastReceived)/seconds,
+			float64(s.FiredTotal-lastFired)/seconds,
+			float64(s.ResolvedTotal-lastResolved)/seconds,
+			s.ActiveAlerts,
+		)
+		lastReceived, lastFired, lastResolved = s.ReceivedTotal, s.FiredTotal, s.ResolvedTotal
+	}
+}
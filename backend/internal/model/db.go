@@ -1,10 +1,13 @@
 package model
 
 import (
+	"errors"
 	"log"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -21,7 +24,7 @@ func InitDB(dbPath string) error {
 	}
 
 	// 自动迁移表结构
-	err = DB.AutoMigrate(&Sample{}, &SystemConfig{}, &TrainingLog{})
+	err = DB.AutoMigrate(&Sample{}, &SystemConfig{}, &TrainingLog{}, &Clip{}, &NotificationLog{}, &NodeHeartbeat{})
 	if err != nil {
 		return err
 	}
@@ -106,3 +109,80 @@ func GetLatestTrainingLog() (*TrainingLog, error) {
 	}
 	return &trainingLog, nil
 }
+
+// CreateClip 创建录像片段记录
+func CreateClip(clip *Clip) error {
+	return DB.Create(clip).Error
+}
+
+// GetClips 按触发时间倒序分页获取录像片段列表，并返回总数
+func GetClips(limit, offset int) ([]Clip, int64, error) {
+	var clips []Clip
+	var total int64
+
+	if err := DB.Model(&Clip{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := DB.Order("triggered_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&clips).Error
+	return clips, total, err
+}
+
+// GetClipByID 按 ID 获取单个录像片段
+func GetClipByID(id uint) (*Clip, error) {
+	var clip Clip
+	if err := DB.First(&clip, id).Error; err != nil {
+		return nil, err
+	}
+	return &clip, nil
+}
+
+// CreateNotificationLog 创建一条报警通知投递记录
+func CreateNotificationLog(logEntry *NotificationLog) error {
+	return DB.Create(logEntry).Error
+}
+
+// UpsertNodeHeartbeat 写入或刷新一个节点的心跳时间
+func UpsertNodeHeartbeat(endpoint string, at time.Time) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_heartbeat_at"}),
+	}).Create(&NodeHeartbeat{Endpoint: endpoint, LastHeartbeatAt: at}).Error
+}
+
+// ListLiveNodeEndpoints 返回心跳时间晚于 since 的节点地址列表
+func ListLiveNodeEndpoints(since time.Time) ([]string, error) {
+	var heartbeats []NodeHeartbeat
+	if err := DB.Where("last_heartbeat_at >= ?", since).Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	endpoints := make([]string, 0, len(heartbeats))
+	for _, h := range heartbeats {
+		endpoints = append(endpoints, h.Endpoint)
+	}
+	return endpoints, nil
+}
+
+// GetSystemConfig 按 key 读取系统配置项，不存在时返回空字符串
+func GetSystemConfig(key string) (string, error) {
+	var cfg SystemConfig
+	err := DB.Where("key = ?", key).First(&cfg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return cfg.Value, nil
+}
+
+// SetSystemConfig 写入或更新一个系统配置项
+func SetSystemConfig(key, value string) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&SystemConfig{Key: key, Value: value}).Error
+}
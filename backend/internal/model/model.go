@@ -67,7 +67,8 @@ type TrainingLog struct {
 	SampleCount int        `json:"sample_count"`                    // 样本数量
 	StartTime   time.Time  `json:"start_time"`                      // 开始时间
 	EndTime     *time.Time `json:"end_time"`                        // 结束时间
-	Status      string     `gorm:"default:'running'" json:"status"` // running/completed/failed
+	Status      string     `gorm:"default:'running'" json:"status"` // running/completed/failed/cancelled
+	Trigger     string     `gorm:"default:'manual'" json:"trigger"` // manual/cron/threshold
 
 	// 模型信息
 	OldModelPath string `json:"old_model_path"` // 旧模型路径
@@ -75,9 +76,60 @@ type TrainingLog struct {
 
 	// 训练结果
 	ErrorMessage string `json:"error_message"` // 错误信息
+	LogFilePath  string `json:"log_file_path"` // 训练进程 stdout/stderr 日志文件路径
 }
 
 // TableName 指定表名
 func (TrainingLog) TableName() string {
 	return "training_logs"
 }
+
+// Clip 报警录像片段记录
+type Clip struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	CameraID        string    `json:"camera_id"`                 // 来源摄像头 ID，单摄像头场景下为空
+	FrameID         int64     `json:"frame_id"`                  // 触发报警的帧序号
+	FilePath        string    `gorm:"not null" json:"file_path"` // MP4 文件路径
+	TriggeredAt     time.Time `json:"triggered_at"`              // 报警触发时间
+	DurationSeconds float64   `json:"duration_seconds"`          // 片段时长（秒），预录 + 续录窗口之和
+}
+
+// TableName 指定表名
+func (Clip) TableName() string {
+	return "clips"
+}
+
+// NotificationLog 报警通知投递记录，每次向某个通知渠道发送（含重试）都追加一条
+type NotificationLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	AlertID     int32  `json:"alert_id"`     // 对应 ws.AlertMessage.ID
+	CameraID    string `json:"camera_id"`    // 来源摄像头 ID，单摄像头场景下为空
+	Channel     string `json:"channel"`      // 渠道配置中的 Name
+	ChannelType string `json:"channel_type"` // webhook/email/dingtalk/kafka
+	Attempt     int    `json:"attempt"`      // 第几次尝试，从 1 开始
+
+	Status       string `gorm:"default:'pending'" json:"status"` // success/failed
+	ErrorMessage string `json:"error_message"`                   // 失败原因，成功时为空
+	DryRun       bool   `json:"dry_run"`                         // 是否为 dry_run 模式下的模拟投递
+}
+
+// TableName 指定表名
+func (NotificationLog) TableName() string {
+	return "notification_logs"
+}
+
+// NodeHeartbeat 集群分片节点心跳表，每个节点周期性地把自己的 Endpoint upsert 一次，
+// naming.Registry 据此判断哪些节点存活并构建一致性哈希环
+type NodeHeartbeat struct {
+	Endpoint        string    `gorm:"primarykey" json:"endpoint"` // 节点对外地址，形如 http://host:8080
+	LastHeartbeatAt time.Time `json:"last_heartbeat_at"`          // 最近一次心跳时间
+}
+
+// TableName 指定表名
+func (NodeHeartbeat) TableName() string {
+	return "node_heartbeats"
+}
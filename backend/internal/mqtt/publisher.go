@@ -0,0 +1,372 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/pipeline"
+	"anomaly_detection_system/backend/internal/training"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+// 重连退避参数，沿用 pipeline 包视频源重连退避的经验值
+const (
+	backoffBase     = 1 * time.Second
+	backoffFactor   = 1.6
+	backoffMaxDelay = 30 * time.Second
+	backoffJitter   = 0.2
+)
+
+// Publisher 把报警消息和各摄像头的周期性状态发布到 MQTT broker，并订阅 {base_topic}/commands/+
+// 主题，让外部控制器像调用 HTTP 接口一样调整帧率、切换视频源或触发训练。
+// Broker 连接断开时按指数退避 + 抖动自动重连，不会影响检测管线本身。
+type Publisher struct {
+	cfg    *config.Config
+	client paho.Client
+
+	videoCapture   pipeline.CaptureBackend
+	captureManager *pipeline.CaptureManager
+	detection      pipeline.DetectionBackend
+	scheduler      *training.Scheduler
+
+	alertChan <-chan *ws.AlertMessage
+
+	stopCh chan struct{}
+}
+
+// NewPublisher 创建 MQTT 发布者；videoCapture/captureManager/detection/scheduler 均可为 nil，
+// 代表对应子系统未启用，届时相关指令与状态上报会被跳过
+func NewPublisher(cfg *config.Config, videoCapture pipeline.CaptureBackend, captureManager *pipeline.CaptureManager, detection pipeline.DetectionBackend, scheduler *training.Scheduler, alertChan <-chan *ws.AlertMessage) *Publisher {
+	return &Publisher{
+		cfg:            cfg,
+		videoCapture:   videoCapture,
+		captureManager: captureManager,
+		detection:      detection,
+		scheduler:      scheduler,
+		alertChan:      alertChan,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start 在 cfg.GetMQTT().Enable 为 true 时连接 broker 并启动报警转发、状态上报两个协程；
+// Enable 为 false 时直接返回 nil，不做任何事
+func (p *Publisher) Start() error {
+	mqttConfig := p.cfg.GetMQTT()
+	if !mqttConfig.Enable {
+		return nil
+	}
+
+	if err := p.connect(mqttConfig); err != nil {
+		return err
+	}
+
+	go p.forwardAlerts()
+	go p.reportStatusLoop()
+
+	return nil
+}
+
+// connect 按配置建立到 broker 的连接，成功后订阅 commands/+ 主题
+func (p *Publisher) connect(mqttConfig config.MQTTConfig) error {
+	opts := paho.NewClientOptions().
+		AddBroker(mqttConfig.BrokerURL).
+		SetClientID(mqttConfig.ClientID).
+		SetUsername(mqttConfig.Username).
+		SetPassword(mqttConfig.Password).
+		SetAutoReconnect(false). // 重连由 reconnectLoop 接管，便于打印退避日志
+		SetConnectionLostHandler(p.onConnectionLost)
+
+	if mqttConfig.TLS {
+		opts.SetTLSConfig(&tls.Config{})
+	}
+
+	p.client = paho.NewClient(opts)
+	if token := p.client.Connect(); token.Wait() && token.Error() != nil {
+		return fmt.Errorf("连接 MQTT broker 失败: %w", token.Error())
+	}
+
+	log.Printf("[MQTTPublisher] 已连接到 %s", mqttConfig.BrokerURL)
+	p.subscribeCommands(mqttConfig)
+	return nil
+}
+
+// onConnectionLost 断线时异步发起重连，避免阻塞 paho 内部的回调协程
+func (p *Publisher) onConnectionLost(_ paho.Client, err error) {
+	log.Printf("[MQTTPublisher] 与 broker 的连接已断开: %v", err)
+	go p.reconnectLoop()
+}
+
+// reconnectLoop 按指数退避 + 抖动持续重试，直到重连成功或 Stop 被调用
+func (p *Publisher) reconnectLoop() {
+	mqttConfig := p.cfg.GetMQTT()
+	for attempt := 0; ; attempt++ {
+		delay := reconnectDelay(attempt)
+		select {
+		case <-p.stopCh:
+			return
+		case <-time.After(delay):
+		}
+
+		if err := p.connect(mqttConfig); err != nil {
+			log.Printf("[MQTTPublisher] 第 %d 次重连失败: %v", attempt+1, err)
+			continue
+		}
+		log.Println("[MQTTPublisher] 重连成功")
+		return
+	}
+}
+
+// reconnectDelay 计算第 attempt 次重连（从 0 开始）前应等待的时间，算法与 pipeline 包的
+// 视频源重连退避一致：delay = min(base * factor^attempt, maxDelay) * (1 + rand*jitter)
+func reconnectDelay(attempt int) time.Duration {
+	delay := float64(backoffBase) * math.Pow(backoffFactor, float64(attempt))
+	if delay > float64(backoffMaxDelay) {
+		delay = float64(backoffMaxDelay)
+	}
+	delay *= 1 + rand.Float64()*backoffJitter
+	return time.Duration(delay)
+}
+
+// alertPayload 与 ws.AlertMessage 字段一致但去掉 ImageData，截图单独发布到 {topic}/image
+type alertPayload struct {
+	ID         int32   `json:"id"`
+	CameraID   string  `json:"camera_id"`
+	FrameID    int64   `json:"frame_id"`
+	Timestamp  int64   `json:"timestamp"`
+	X1         float32 `json:"x1"`
+	Y1         float32 `json:"y1"`
+	X2         float32 `json:"x2"`
+	Y2         float32 `json:"y2"`
+	ClassName  string  `json:"class_name"`
+	Confidence float32 `json:"confidence"`
+	Entropy    float32 `json:"entropy"`
+}
+
+// forwardAlerts 把 alertChan 里的每条报警发布到 {base_topic}/alerts/{camera_id}，
+// 若带有截图则额外发布到 {base_topic}/alerts/{camera_id}/image
+func (p *Publisher) forwardAlerts() {
+	for alert := range p.alertChan {
+		mqttConfig := p.cfg.GetMQTT()
+		topic := fmt.Sprintf("%s/alerts/%s", mqttConfig.BaseTopic, topicSegment(alert.CameraID))
+
+		payload, err := json.Marshal(alertPayload{
+			ID:         alert.ID,
+			CameraID:   alert.CameraID,
+			FrameID:    alert.FrameID,
+			Timestamp:  alert.Timestamp,
+			X1:         alert.X1,
+			Y1:         alert.Y1,
+			X2:         alert.X2,
+			Y2:         alert.Y2,
+			ClassName:  alert.ClassName,
+			Confidence: alert.Confidence,
+			Entropy:    alert.Entropy,
+		})
+		if err != nil {
+			log.Printf("[MQTTPublisher] 序列化报警失败: %v", err)
+			continue
+		}
+
+		p.publish(topic, mqttConfig.QoS, payload)
+
+		if alert.ImageData != "" {
+			p.publish(topic+"/image", mqttConfig.QoS, []byte(alert.ImageData))
+		}
+	}
+}
+
+// reportStatusLoop 按 StatusIntervalSeconds 周期性发布各摄像头状态，直到 Stop 被调用
+func (p *Publisher) reportStatusLoop() {
+	mqttConfig := p.cfg.GetMQTT()
+	interval := time.Duration(mqttConfig.StatusIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			p.reportStatus()
+		}
+	}
+}
+
+// reportStatus 发布单摄像头场景（videoCapture）与多摄像头场景（captureManager 下每路摄像头）的状态
+func (p *Publisher) reportStatus() {
+	var backendStats map[string]interface{}
+	if p.detection != nil {
+		backendStats = p.detection.Stats()
+	}
+
+	if p.videoCapture != nil {
+		p.publishStatus("", p.videoCapture.GetStats(), backendStats)
+	}
+
+	if p.captureManager != nil {
+		for cameraID, stats := range p.captureManager.Stats() {
+			p.publishStatus(cameraID, stats, backendStats)
+		}
+	}
+}
+
+// publishStatus 把采集统计与检测后端统计合并后发布到 {base_topic}/status/{camera_id}
+func (p *Publisher) publishStatus(cameraID string, captureStats map[string]interface{}, backendStats map[string]interface{}) {
+	mqttConfig := p.cfg.GetMQTT()
+
+	status := map[string]interface{}{
+		"capture":   captureStats,
+		"detection": backendStats,
+		"time":      time.Now().Format(time.RFC3339),
+	}
+
+	payload, err := json.Marshal(status)
+	if err != nil {
+		log.Printf("[MQTTPublisher] 序列化状态失败: %v", err)
+		return
+	}
+
+	topic := fmt.Sprintf("%s/status/%s", mqttConfig.BaseTopic, topicSegment(cameraID))
+	p.publish(topic, mqttConfig.QoS, payload)
+}
+
+// commandPayload {base_topic}/commands/{camera_id} 主题的指令格式
+type commandPayload struct {
+	Action string              `json:"action"` // "set_fps" | "switch_source" | "trigger_training"
+	FPS    int                 `json:"fps,omitempty"`
+	Source *config.VideoConfig `json:"source,omitempty"`
+}
+
+// subscribeCommands 订阅 {base_topic}/commands/+，camera_id 由主题通配符捕获的最后一段决定
+func (p *Publisher) subscribeCommands(mqttConfig config.MQTTConfig) {
+	topic := fmt.Sprintf("%s/commands/+", mqttConfig.BaseTopic)
+	if token := p.client.Subscribe(topic, mqttConfig.QoS, p.handleCommand); token.Wait() && token.Error() != nil {
+		log.Printf("[MQTTPublisher] 订阅 %s 失败: %v", topic, token.Error())
+	}
+}
+
+// handleCommand 解析并执行一条外部指令：调整帧率、切换视频源或触发训练
+func (p *Publisher) handleCommand(_ paho.Client, msg paho.Message) {
+	cameraID := cameraIDFromTopic(msg.Topic())
+
+	var cmd commandPayload
+	if err := json.Unmarshal(msg.Payload(), &cmd); err != nil {
+		log.Printf("[MQTTPublisher] 指令解析失败 (topic=%s): %v", msg.Topic(), err)
+		return
+	}
+
+	switch cmd.Action {
+	case "set_fps":
+		backend := p.backendForCamera(cameraID)
+		if backend == nil {
+			log.Printf("[MQTTPublisher] 摄像头 %q 不存在，忽略 set_fps 指令", cameraID)
+			return
+		}
+		if err := backend.SetFPS(cmd.FPS); err != nil {
+			log.Printf("[MQTTPublisher] set_fps 失败 (camera=%q): %v", cameraID, err)
+		}
+	case "switch_source":
+		if cmd.Source == nil {
+			log.Printf("[MQTTPublisher] switch_source 指令缺少 source 字段")
+			return
+		}
+		p.switchSource(cameraID, *cmd.Source)
+	case "trigger_training":
+		if p.scheduler != nil {
+			p.scheduler.TriggerManual()
+		}
+	default:
+		log.Printf("[MQTTPublisher] 未知指令: %s", cmd.Action)
+	}
+}
+
+// switchSource 按指令更新视频源配置并重启对应摄像头的采集，空 cameraID 对应单摄像头场景
+func (p *Publisher) switchSource(cameraID string, videoConfig config.VideoConfig) {
+	if cameraID == "" {
+		p.cfg.UpdateVideo(videoConfig)
+		if p.videoCapture != nil {
+			if err := p.videoCapture.Restart(); err != nil {
+				log.Printf("[MQTTPublisher] 切换视频源失败: %v", err)
+			}
+		}
+		return
+	}
+
+	if p.captureManager == nil {
+		log.Printf("[MQTTPublisher] 多摄像头管理器未启用，忽略摄像头 %q 的 switch_source 指令", cameraID)
+		return
+	}
+	videoConfig.CameraID = cameraID
+	if err := p.cfg.UpdateCamera(cameraID, videoConfig); err != nil {
+		log.Printf("[MQTTPublisher] 更新摄像头 %q 配置失败: %v", cameraID, err)
+		return
+	}
+	if err := p.captureManager.RestartCamera(cameraID); err != nil {
+		log.Printf("[MQTTPublisher] 重启摄像头 %q 失败: %v", cameraID, err)
+	}
+}
+
+// backendForCamera 按 CameraID 返回对应的采集后端，空字符串对应单摄像头场景的 videoCapture
+func (p *Publisher) backendForCamera(cameraID string) pipeline.CaptureBackend {
+	if cameraID == "" {
+		return p.videoCapture
+	}
+	if p.captureManager == nil {
+		return nil
+	}
+	backend, ok := p.captureManager.Camera(cameraID)
+	if !ok {
+		return nil
+	}
+	return backend
+}
+
+// topicSegment 把 CameraID 映射为主题里的一段，单摄像头场景（CameraID 为空）用 "default" 占位
+func topicSegment(cameraID string) string {
+	if cameraID == "" {
+		return "default"
+	}
+	return cameraID
+}
+
+// cameraIDFromTopic 从 {base_topic}/commands/{segment} 主题里取出 segment 并还原为 CameraID，
+// "default" 还原为空字符串，对应单摄像头场景
+func cameraIDFromTopic(topic string) string {
+	idx := strings.LastIndex(topic, "/")
+	segment := topic
+	if idx >= 0 {
+		segment = topic[idx+1:]
+	}
+	if segment == "default" {
+		return ""
+	}
+	return segment
+}
+
+// publish 发布一条消息，失败只记录日志，不阻塞调用方
+func (p *Publisher) publish(topic string, qos byte, payload []byte) {
+	if token := p.client.Publish(topic, qos, false, payload); token.Wait() && token.Error() != nil {
+		log.Printf("[MQTTPublisher] 发布 %s 失败: %v", topic, token.Error())
+	}
+}
+
+// Stop 停止后台协程并断开与 broker 的连接
+func (p *Publisher) Stop() {
+	close(p.stopCh)
+	if p.client != nil && p.client.IsConnected() {
+		p.client.Disconnect(250)
+	}
+}
@@ -0,0 +1,49 @@
+package naming
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const forwardTimeout = 5 * time.Second
+
+// Forwarder 把不归本节点处理的检测结果 / 再平衡时需要交接的轨迹状态通过 HTTP POST
+// 转发给哈希环上实际负责该 key 的节点。具体请求/响应结构由调用方（filter 包）决定，
+// Forwarder 只负责统一的序列化、超时与错误包装
+type Forwarder struct {
+	client *http.Client
+}
+
+// NewForwarder 创建转发器
+func NewForwarder() *Forwarder {
+	return &Forwarder{client: &http.Client{Timeout: forwardTimeout}}
+}
+
+// PostJSON 把 payload 序列化为 JSON 并 POST 到 endpoint+path
+func (f *Forwarder) PostJSON(endpoint, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化转发请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("节点 %s 返回非 2xx 状态码: %d", endpoint, resp.StatusCode)
+	}
+	return nil
+}
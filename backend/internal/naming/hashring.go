@@ -0,0 +1,102 @@
+package naming
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// defaultVirtualReplicas 是 ClusterConfig.VirtualReplicas 未配置时的兜底值
+const defaultVirtualReplicas = 100
+
+// HashRing 是一个按节点地址分片的一致性哈希环：每个节点映射若干虚拟节点以平滑分片，
+// 仿 nightingale naming.HashRing，用于把同一个 key（stream_id/CameraID）稳定地路由到同一台节点，
+// 节点增减时只影响环上相邻的一小段 key，不会导致全量重新分片
+type HashRing struct {
+	mu        sync.RWMutex
+	replicas  int
+	sortedSet []uint32          // 排序后的虚拟节点哈希值，便于二分查找
+	hashMap   map[uint32]string // 虚拟节点哈希值 -> 真实节点地址
+	nodes     map[string]bool   // 当前环上的真实节点集合，便于判断成员是否变化
+}
+
+// NewHashRing 创建一致性哈希环；replicas<=0 时使用默认虚拟节点数
+func NewHashRing(replicas int) *HashRing {
+	if replicas <= 0 {
+		replicas = defaultVirtualReplicas
+	}
+	return &HashRing{
+		replicas: replicas,
+		hashMap:  make(map[uint32]string),
+		nodes:    make(map[string]bool),
+	}
+}
+
+// SetNodes 用给定的节点列表整体重建哈希环。相比逐个 AddNode/RemoveNode，
+// 整体重建避免了心跳轮询期间多次局部调整导致的中间态不一致
+func (r *HashRing) SetNodes(nodes []string) {
+	sortedSet := make([]uint32, 0, len(nodes)*r.replicas)
+	hashMap := make(map[uint32]string, len(nodes)*r.replicas)
+	nodeSet := make(map[string]bool, len(nodes))
+
+	for _, node := range nodes {
+		if node == "" {
+			continue
+		}
+		nodeSet[node] = true
+		for i := 0; i < r.replicas; i++ {
+			h := hashKey(node + "#" + strconv.Itoa(i))
+			hashMap[h] = node
+			sortedSet = append(sortedSet, h)
+		}
+	}
+	sort.Slice(sortedSet, func(i, j int) bool { return sortedSet[i] < sortedSet[j] })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sortedSet = sortedSet
+	r.hashMap = hashMap
+	r.nodes = nodeSet
+}
+
+// GetNode 返回 key 在哈希环上顺时针遇到的第一个节点；环为空时返回 ok=false
+func (r *HashRing) GetNode(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.sortedSet) == 0 {
+		return "", false
+	}
+
+	h := hashKey(key)
+	idx := sort.Search(len(r.sortedSet), func(i int) bool { return r.sortedSet[i] >= h })
+	if idx == len(r.sortedSet) {
+		idx = 0
+	}
+	return r.hashMap[r.sortedSet[idx]], true
+}
+
+// Nodes 返回当前环上的真实节点列表
+func (r *HashRing) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make([]string, 0, len(r.nodes))
+	for node := range r.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// HasNode 判断某个节点当前是否在环上
+func (r *HashRing) HasNode(node string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.nodes[node]
+}
+
+func hashKey(s string) uint32 {
+	return crc32.ChecksumIEEE([]byte(s))
+}
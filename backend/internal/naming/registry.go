@@ -0,0 +1,175 @@
+package naming
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/model"
+)
+
+// 心跳/刷新参数默认值：ClusterConfig 对应字段未配置时的兜底值
+const (
+	defaultHeartbeatInterval = 5 * time.Second
+	defaultNodeTimeout       = 15 * time.Second
+	defaultRefreshInterval   = 5 * time.Second
+
+	changesChanSize = 1 // 只需要"有变化"这一个信号，旧信号丢失也没关系，下次轮询会重新比对
+)
+
+// Registry 负责把本节点的存活状态写入 node_heartbeats 表，并周期性读取全部存活节点、
+// 重建一致性哈希环；ClusterConfig.Enable 为 false 时 Start 直接跳过，Ring() 退化为
+// 只包含本节点（若配置了 SelfEndpoint）或为空的环，GetNode 恒定返回本节点
+type Registry struct {
+	cfg  *config.Config
+	ring *HashRing
+
+	mu       sync.RWMutex
+	lastSeen []string // 上一次刷新得到的存活节点列表，用于判断成员是否发生变化
+
+	changes chan struct{} // 成员发生变化时发出信号，AlertFilter 据此触发再平衡
+	stopCh  chan struct{}
+}
+
+// NewRegistry 创建分片节点注册表
+func NewRegistry(cfg *config.Config) *Registry {
+	return &Registry{
+		cfg:     cfg,
+		ring:    NewHashRing(cfg.GetCluster().VirtualReplicas),
+		changes: make(chan struct{}, changesChanSize),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Start 在 cfg.GetCluster().Enable 为 true 时启动心跳上报与成员刷新两个协程
+func (r *Registry) Start() {
+	cluster := r.cfg.GetCluster()
+	if !cluster.Enable {
+		return
+	}
+	if cluster.SelfEndpoint == "" {
+		log.Printf("[naming] cluster.enable=true 但 self_endpoint 为空，跳过分片注册")
+		return
+	}
+
+	go r.heartbeatLoop()
+	go r.refreshLoop()
+}
+
+// Stop 停止心跳上报与成员刷新协程
+func (r *Registry) Stop() {
+	close(r.stopCh)
+}
+
+// Ring 返回当前的一致性哈希环，供 AlertFilter 判断某个 key 是否归本节点处理
+func (r *Registry) Ring() *HashRing {
+	return r.ring
+}
+
+// SelfEndpoint 返回本节点地址
+func (r *Registry) SelfEndpoint() string {
+	return r.cfg.GetCluster().SelfEndpoint
+}
+
+// Enabled 返回分片功能是否开启
+func (r *Registry) Enabled() bool {
+	return r.cfg.GetCluster().Enable
+}
+
+// Changes 返回成员变化信号通道，AlertFilter 据此决定是否需要对已追踪的轨迹做再平衡
+func (r *Registry) Changes() <-chan struct{} {
+	return r.changes
+}
+
+func (r *Registry) heartbeatInterval() time.Duration {
+	seconds := r.cfg.GetCluster().HeartbeatIntervalSeconds
+	if seconds <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (r *Registry) nodeTimeout() time.Duration {
+	seconds := r.cfg.GetCluster().NodeTimeoutSeconds
+	if seconds <= 0 {
+		return defaultNodeTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (r *Registry) refreshInterval() time.Duration {
+	seconds := r.cfg.GetCluster().RefreshIntervalSeconds
+	if seconds <= 0 {
+		return defaultRefreshInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// heartbeatLoop 周期性把本节点地址 upsert 进 node_heartbeats 表
+func (r *Registry) heartbeatLoop() {
+	self := r.SelfEndpoint()
+	if err := model.UpsertNodeHeartbeat(self, time.Now()); err != nil {
+		log.Printf("[naming] 写入心跳失败: %v", err)
+	}
+
+	ticker := time.NewTicker(r.heartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			if err := model.UpsertNodeHeartbeat(self, time.Now()); err != nil {
+				log.Printf("[naming] 写入心跳失败: %v", err)
+			}
+		}
+	}
+}
+
+// refreshLoop 周期性读取存活节点列表并重建哈希环，成员发生变化时往 changes 通道发一个信号
+func (r *Registry) refreshLoop() {
+	r.refresh()
+
+	ticker := time.NewTicker(r.refreshInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			r.refresh()
+		}
+	}
+}
+
+func (r *Registry) refresh() {
+	since := time.Now().Add(-r.nodeTimeout())
+	nodes, err := model.ListLiveNodeEndpoints(since)
+	if err != nil {
+		log.Printf("[naming] 读取存活节点列表失败: %v", err)
+		return
+	}
+
+	r.ring.SetNodes(nodes)
+
+	sorted := append([]string(nil), nodes...)
+	sort.Strings(sorted)
+
+	r.mu.Lock()
+	changed := strings.Join(sorted, ",") != strings.Join(r.lastSeen, ",")
+	r.lastSeen = sorted
+	r.mu.Unlock()
+
+	if changed {
+		log.Printf("[naming] 集群成员发生变化，当前存活节点: %v", sorted)
+		select {
+		case r.changes <- struct{}{}:
+		default:
+		}
+	}
+}
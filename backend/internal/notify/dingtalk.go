@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+const dingTalkTimeout = 5 * time.Second
+
+// DingTalkNotifier 把报警消息推送到钉钉/飞书自定义机器人的 webhook，Secret 非空时按加签校验要求签名
+type DingTalkNotifier struct {
+	webhookURL string
+	secret     string
+	client     *http.Client
+}
+
+// NewDingTalkNotifier 创建钉钉/飞书机器人通知器
+func NewDingTalkNotifier(channel config.NotifyChannelConfig) *DingTalkNotifier {
+	return &DingTalkNotifier{
+		webhookURL: channel.WebhookURL,
+		secret:     channel.Secret,
+		client:     &http.Client{Timeout: dingTalkTimeout},
+	}
+}
+
+// dingTalkTextMessage 钉钉自定义机器人的 text 消息格式
+type dingTalkTextMessage struct {
+	MsgType string `json:"msgtype"`
+	Text    struct {
+		Content string `json:"content"`
+	} `json:"text"`
+}
+
+// Send 实现 Notifier
+func (n *DingTalkNotifier) Send(ctx context.Context, alert *ws.AlertMessage) error {
+	content := fmt.Sprintf(
+		"[%s] 摄像头 %s 触发报警\n类别: %s (置信度 %.2f)\n帧号: %d  策略: %d  事件: %s",
+		alert.Severity, alert.CameraID, alert.ClassName, alert.Confidence, alert.FrameID, alert.StrategyID, alert.EventType,
+	)
+
+	var msg dingTalkTextMessage
+	msg.MsgType = "text"
+	msg.Text.Content = content
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化钉钉消息失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.signedURL(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("钉钉机器人返回非 2xx 状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signedURL 按钉钉加签算法在 webhookURL 后追加 timestamp 和 sign 查询参数，Secret 为空时原样返回
+func (n *DingTalkNotifier) signedURL() string {
+	if n.secret == "" {
+		return n.webhookURL
+	}
+
+	timestamp := time.Now().UnixMilli()
+	stringToSign := fmt.Sprintf("%d\n%s", timestamp, n.secret)
+
+	mac := hmac.New(sha256.New, []byte(n.secret))
+	mac.Write([]byte(stringToSign))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	sep := "?"
+	if strings.Contains(n.webhookURL, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%stimestamp=%d&sign=%s", n.webhookURL, sep, timestamp, url.QueryEscape(sign))
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/metrics"
+	"anomaly_detection_system/backend/internal/model"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+// 单条通知的重试退避参数，与 mqtt 包的重连退避算法一致：delay = min(base * factor^attempt, maxDelay) * (1 + rand*jitter)
+const (
+	retryBackoffBase   = 500 * time.Millisecond
+	retryBackoffFactor = 2.0
+	retryBackoffMax    = 10 * time.Second
+	retryBackoffJitter = 0.2
+
+	defaultMaxRetries = 3
+	defaultQueueSize  = 50
+)
+
+// channelWorker 维护一个通知渠道的待发送队列和独立的发送协程
+type channelWorker struct {
+	name        string
+	channelType string
+	notifier    Notifier
+	maxRetries  int
+	dryRun      bool
+
+	queue chan *ws.AlertMessage
+}
+
+// Dispatcher 把命中的报警异步分发给所有已启用的通知渠道，渠道之间互不阻塞：
+// 某个渠道队列已满只丢弃它自己最旧的一条待发消息，不影响其余渠道
+type Dispatcher struct {
+	workers []*channelWorker
+}
+
+// NewDispatcher 按 cfg.GetNotify() 中已启用的渠道构建 Dispatcher 并启动各渠道的发送协程；
+// 渠道构造失败只记录日志并跳过该渠道，不影响其余渠道可用
+func NewDispatcher(cfg *config.Config) *Dispatcher {
+	notifyConfig := cfg.GetNotify()
+
+	d := &Dispatcher{}
+	for _, channel := range notifyConfig.Channels {
+		if !channel.Enabled {
+			continue
+		}
+
+		notifier, err := newNotifier(channel)
+		if err != nil {
+			log.Printf("[NotifyDispatcher] 渠道 %q 初始化失败: %v", channel.Name, err)
+			continue
+		}
+
+		maxRetries := channel.MaxRetries
+		if maxRetries <= 0 {
+			maxRetries = defaultMaxRetries
+		}
+		queueSize := channel.QueueSize
+		if queueSize <= 0 {
+			queueSize = defaultQueueSize
+		}
+
+		worker := &channelWorker{
+			name:        channel.Name,
+			channelType: channel.Type,
+			notifier:    notifier,
+			maxRetries:  maxRetries,
+			dryRun:      notifyConfig.DryRun,
+			queue:       make(chan *ws.AlertMessage, queueSize),
+		}
+		d.workers = append(d.workers, worker)
+		go worker.run()
+	}
+
+	return d
+}
+
+// Dispatch 把一条报警消息非阻塞地投递给所有渠道的队列，队满时丢弃该队列里最旧的一条再入队
+func (d *Dispatcher) Dispatch(alert *ws.AlertMessage) {
+	for _, w := range d.workers {
+		select {
+		case w.queue <- alert:
+		default:
+			select {
+			case <-w.queue:
+			default:
+			}
+			select {
+			case w.queue <- alert:
+			default:
+			}
+			log.Printf("[NotifyDispatcher] 渠道 %q 队列已满，丢弃最旧的一条报警", w.name)
+		}
+	}
+}
+
+// run 持续消费该渠道的待发队列，逐条发送（含重试）
+func (w *channelWorker) run() {
+	for alert := range w.queue {
+		w.sendWithRetry(alert)
+	}
+}
+
+// sendWithRetry 按退避策略重试发送一条报警，每次尝试（含 dry_run 下的模拟尝试）都落一条 NotificationLog
+func (w *channelWorker) sendWithRetry(alert *ws.AlertMessage) {
+	for attempt := 1; attempt <= w.maxRetries; attempt++ {
+		if attempt > 1 {
+			time.Sleep(retryDelay(attempt - 2))
+		}
+
+		var sendErr error
+		if !w.dryRun {
+			sendErr = w.notifier.Send(context.Background(), alert)
+		}
+
+		w.logAttempt(alert, attempt, sendErr)
+
+		if sendErr == nil {
+			return
+		}
+		log.Printf("[NotifyDispatcher] 渠道 %q 第 %d 次投递报警 %d 失败: %v", w.name, attempt, alert.ID, sendErr)
+	}
+}
+
+// logAttempt 把本次投递结果写入 NotificationLog，失败只记录日志不影响发送流程本身
+func (w *channelWorker) logAttempt(alert *ws.AlertMessage, attempt int, sendErr error) {
+	status := "success"
+	errMsg := ""
+	if sendErr != nil {
+		status = "failed"
+		errMsg = sendErr.Error()
+	}
+
+	entry := &model.NotificationLog{
+		AlertID:      alert.ID,
+		CameraID:     alert.CameraID,
+		Channel:      w.name,
+		ChannelType:  w.channelType,
+		Attempt:      attempt,
+		Status:       status,
+		ErrorMessage: errMsg,
+		DryRun:       w.dryRun,
+	}
+	if err := model.CreateNotificationLog(entry); err != nil {
+		log.Printf("[NotifyDispatcher] 写入 NotificationLog 失败: %v", err)
+	}
+
+	metrics.RecordNotifySend(w.name, status)
+}
+
+// retryDelay 计算第 retryIndex 次重试（从 0 开始）前应等待的时间
+func retryDelay(retryIndex int) time.Duration {
+	delay := float64(retryBackoffBase) * math.Pow(retryBackoffFactor, float64(retryIndex))
+	if delay > float64(retryBackoffMax) {
+		delay = float64(retryBackoffMax)
+	}
+	delay *= 1 + rand.Float64()*retryBackoffJitter
+	return time.Duration(delay)
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+// EmailNotifier 通过 SMTP 把报警消息以纯文本邮件发送给固定收件人列表
+type EmailNotifier struct {
+	host string
+	port int
+	user string
+	pass string
+	from string
+	to   []string
+}
+
+// NewEmailNotifier 创建 SMTP 邮件通知器
+func NewEmailNotifier(channel config.NotifyChannelConfig) *EmailNotifier {
+	return &EmailNotifier{
+		host: channel.SMTPHost,
+		port: channel.SMTPPort,
+		user: channel.SMTPUser,
+		pass: channel.SMTPPass,
+		from: channel.EmailFrom,
+		to:   channel.EmailTo,
+	}
+}
+
+// Send 实现 Notifier；ctx 目前未被 net/smtp 使用，仅为满足接口签名保留
+func (n *EmailNotifier) Send(_ context.Context, alert *ws.AlertMessage) error {
+	if len(n.to) == 0 {
+		return fmt.Errorf("邮件通知渠道未配置收件人")
+	}
+
+	subject := fmt.Sprintf("[%s] 摄像头 %s 触发报警", alert.Severity, alert.CameraID)
+	body := fmt.Sprintf(
+		"报警 ID: %d\n摄像头: %s\n帧号: %d\n类别: %s (置信度 %.2f)\n策略 ID: %d\n事件类型: %s\n",
+		alert.ID, alert.CameraID, alert.FrameID, alert.ClassName, alert.Confidence, alert.StrategyID, alert.EventType,
+	)
+	msg := buildPlainTextMessage(n.from, n.to, subject, body)
+
+	addr := fmt.Sprintf("%s:%d", n.host, n.port)
+	var auth smtp.Auth
+	if n.user != "" {
+		auth = smtp.PlainAuth("", n.user, n.pass, n.host)
+	}
+	return smtp.SendMail(addr, auth, n.from, n.to, msg)
+}
+
+// buildPlainTextMessage 拼出一封最简单的纯文本邮件的 RFC 5322 报文
+func buildPlainTextMessage(from string, to []string, subject, body string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ","))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(body)
+	return []byte(b.String())
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+// KafkaNotifier 把报警消息作为一条 Kafka 消息发布到配置的 topic
+type KafkaNotifier struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaNotifier 创建 Kafka 通知器；Brokers/Topic 为空时 writer 可以构造但发送必然失败，由 Dispatcher 的重试日志体现
+func NewKafkaNotifier(channel config.NotifyChannelConfig) *KafkaNotifier {
+	return &KafkaNotifier{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(channel.Brokers...),
+			Topic:    channel.Topic,
+			Balancer: &kafka.LeastBytes{},
+		},
+	}
+}
+
+// Send 实现 Notifier
+func (n *KafkaNotifier) Send(ctx context.Context, alert *ws.AlertMessage) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("序列化报警消息失败: %w", err)
+	}
+
+	return n.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(alert.CameraID),
+		Value: payload,
+	})
+}
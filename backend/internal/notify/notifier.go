@@ -0,0 +1,33 @@
+// Package notify 实现报警通知的分发：AlertFilter 命中报警后，按 config.NotifyConfig 中配置的渠道
+// 异步投递给 webhook/email/dingtalk/kafka 等下游，每个渠道独立重试、独立限流，互不阻塞。
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+// Notifier 是一个通知渠道的发送端点，实现方只需关心把一条报警消息投递到自己的下游
+type Notifier interface {
+	// Send 发送一条报警消息，失败返回非 nil error 以触发 Dispatcher 的重试
+	Send(ctx context.Context, alert *ws.AlertMessage) error
+}
+
+// newNotifier 按渠道类型构造对应的 Notifier 实现
+func newNotifier(channel config.NotifyChannelConfig) (Notifier, error) {
+	switch channel.Type {
+	case "webhook":
+		return NewWebhookNotifier(channel), nil
+	case "email":
+		return NewEmailNotifier(channel), nil
+	case "dingtalk", "feishu":
+		return NewDingTalkNotifier(channel), nil
+	case "kafka":
+		return NewKafkaNotifier(channel), nil
+	default:
+		return nil, fmt.Errorf("未知的通知渠道类型: %q", channel.Type)
+	}
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// WebhookNotifier 把报警消息原样序列化为 JSON 并 POST 到配置的 URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier 创建 HTTP webhook 通知器
+func NewWebhookNotifier(channel config.NotifyChannelConfig) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    channel.URL,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Send 实现 Notifier
+func (n *WebhookNotifier) Send(ctx context.Context, alert *ws.AlertMessage) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("序列化报警消息失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回非 2xx 状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package pipeline
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// FPSController 为单路摄像头维护自适应帧率状态：场景持续空闲 idleWindow 后把采集帧率
+// 降到 idleFPS，检测到活动后立刻恢复到 normalFPS。活动判定依据与上一帧的检测结果对比：
+// 检测框数量变化，或任意检测框在上一帧里找不到同类别且 IoU 达标的对应框，都视为发生了移动。
+type FPSController struct {
+	backend    CaptureBackend
+	normalFPS  int
+	idleFPS    int
+	idleWindow time.Duration
+
+	mu         sync.Mutex
+	lastActive time.Time
+	lastDets   []*Detection
+	idle       bool
+}
+
+// NewFPSController 创建自适应帧率控制器；idleWindow<=0 时 Feed 直接忽略所有输入
+func NewFPSController(backend CaptureBackend, normalFPS, idleFPS int, idleWindow time.Duration) *FPSController {
+	if idleFPS <= 0 {
+		idleFPS = 2
+	}
+	return &FPSController{
+		backend:    backend,
+		normalFPS:  normalFPS,
+		idleFPS:    idleFPS,
+		idleWindow: idleWindow,
+		lastActive: time.Now(),
+	}
+}
+
+// Feed 提交一次检测结果：发现活动则恢复正常帧率，持续空闲超过 idleWindow 则降帧
+func (c *FPSController) Feed(result *DetectionResult, motionIoUThreshold float32) {
+	if c.idleWindow <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	active := c.hasMotion(result.Detections, motionIoUThreshold)
+	c.lastDets = result.Detections
+
+	if active {
+		c.lastActive = time.Now()
+		if c.idle {
+			c.idle = false
+			if err := c.backend.SetFPS(c.normalFPS); err != nil {
+				log.Printf("[FPSController] 恢复正常帧率失败: %v", err)
+			} else {
+				log.Printf("[FPSController] 检测到活动，恢复帧率至 %d", c.normalFPS)
+			}
+		}
+		return
+	}
+
+	if !c.idle && time.Since(c.lastActive) > c.idleWindow {
+		c.idle = true
+		if err := c.backend.SetFPS(c.idleFPS); err != nil {
+			log.Printf("[FPSController] 降低帧率失败: %v", err)
+		} else {
+			log.Printf("[FPSController] 场景持续空闲超过 %v，降低帧率至 %d", c.idleWindow, c.idleFPS)
+		}
+	}
+}
+
+// hasMotion 判断本帧相对上一帧是否发生了移动：检测框数量不同，
+// 或存在检测框在上一帧找不到同类别且 IoU 达到 threshold 的对应框
+func (c *FPSController) hasMotion(dets []*Detection, threshold float32) bool {
+	if len(dets) != len(c.lastDets) {
+		return true
+	}
+	if threshold <= 0 {
+		threshold = 0.9
+	}
+	for _, d := range dets {
+		matched := false
+		for _, prev := range c.lastDets {
+			if d.ClassID == prev.ClassID && iou(d, prev) >= threshold {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return true
+		}
+	}
+	return false
+}
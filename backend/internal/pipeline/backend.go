@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+
+	"anomaly_detection_system/backend/internal/config"
+	pb "anomaly_detection_system/backend/pb"
+)
+
+// DetectionBackend 统一的检测后端接口，屏蔽 gRPC / HTTP / 本地推理等具体实现差异，
+// handler 层和管线只依赖该接口，具体实现由 config.AI.BackendType 选择。
+type DetectionBackend interface {
+	// Start 启动后端（建立连接、启动内部协程等）
+	Start() error
+	// Stop 停止后端并释放资源
+	Stop()
+	// Detect 对单帧执行检测
+	Detect(ctx context.Context, frame *Frame) (*DetectionResult, error)
+	// UpdateParams 更新后端运行参数
+	UpdateParams(params *pb.UpdateParamsRequest) (*pb.UpdateParamsResponse, error)
+	// ReloadModel 重新加载模型
+	ReloadModel(modelPath string) (*pb.ReloadModelResponse, error)
+	// Stats 返回后端统计信息
+	Stats() map[string]interface{}
+	// Name 返回后端名称，用于日志与状态展示
+	Name() string
+}
+
+// NewDetectionBackend 根据 config.AI.BackendType 创建对应的检测后端
+func NewDetectionBackend(cfg *config.Config, frameChan chan *Frame, resultChan chan *DetectionResult) DetectionBackend {
+	switch cfg.GetAI().BackendType {
+	case "multi":
+		return NewMultiBackend(cfg, frameChan, resultChan)
+	default:
+		return newNamedDetectionBackend(cfg.GetAI().BackendType, cfg, frameChan, resultChan)
+	}
+}
+
+// newNamedDetectionBackend 按给定的后端类型名创建具体后端，被 NewDetectionBackend 和
+// MultiBackend（按 config.AI.MultiBackendTypes 创建子后端）共用，type 不支持 "multi" 嵌套
+func newNamedDetectionBackend(backendType string, cfg *config.Config, frameChan chan *Frame, resultChan chan *DetectionResult) DetectionBackend {
+	switch backendType {
+	case "http":
+		return NewHTTPBackend(cfg, frameChan, resultChan)
+	case "local":
+		return NewLocalONNXBackend(cfg, frameChan, resultChan)
+	default:
+		return NewGRPCClient(cfg, frameChan, resultChan)
+	}
+}
+
+// bboxCoords 从 AI 服务返回的检测框中取出坐标，bbox 缺失（未知/第三方实现遗漏该字段）时
+// 按零值处理，而不是让调用方直接解引用 nil 指针导致 panic
+func bboxCoords(bbox *pb.BBox) (x1, y1, x2, y2 float32) {
+	if bbox == nil {
+		return 0, 0, 0, 0
+	}
+	return bbox.X1, bbox.Y1, bbox.X2, bbox.Y2
+}
+
+// runBackendLoop 为不自带调度协程的后端提供通用的 frameChan -> backend.Detect -> resultChan 驱动，
+// GRPCClient 有自己的流式调度（dispatchLoop/recvLoop），不使用这个通用驱动。
+func runBackendLoop(ctx context.Context, backend DetectionBackend, frameChan chan *Frame, resultChan chan *DetectionResult, workerCount int) {
+	for i := 0; i < workerCount; i++ {
+		go func(id int) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case frame := <-frameChan:
+					if frame == nil {
+						continue
+					}
+
+					result, err := backend.Detect(ctx, frame)
+					if err != nil {
+						log.Printf("[%s] Worker %d 检测失败: %v", backend.Name(), id, err)
+						continue
+					}
+					if result == nil {
+						continue
+					}
+
+					select {
+					case resultChan <- result:
+					default:
+						log.Printf("[%s] 结果通道已满，丢弃结果", backend.Name())
+					}
+				}
+			}
+		}(i)
+	}
+}
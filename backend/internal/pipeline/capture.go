@@ -0,0 +1,191 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+)
+
+// stallCheckInterval 无帧检测的轮询间隔
+const stallCheckInterval = 1 * time.Second
+
+// CaptureBackend 统一的视频采集后端接口，屏蔽 ffmpeg 子进程 / GoCV / gortsplib2 等具体实现差异，
+// handler 层和管线只依赖该接口，具体实现由 config.Video.Backend 选择。
+type CaptureBackend interface {
+	// Start 启动采集（建立连接、启动内部协程等）
+	Start() error
+	// Stop 停止采集并释放资源
+	Stop()
+	// Restart 重启采集，用于切换视频源或从异常状态恢复
+	Restart() error
+	// IsOpen 返回视频源当前是否处于打开状态
+	IsOpen() bool
+	// GetStats 返回采集统计信息
+	GetStats() map[string]interface{}
+	// Name 返回后端名称，用于日志与状态展示
+	Name() string
+	// SetFPS 动态调整采集帧率（用于自适应帧率：场景空闲时降帧，检测到活动后恢复），
+	// 不支持运行时调帧的后端应返回明确的 error 而不是静默忽略
+	SetFPS(fps int) error
+}
+
+// NewCaptureBackend 根据 cfg.GetVideo().Backend 创建对应的视频采集后端。
+// cfg 既可以是 *config.Config（单摄像头场景），也可以是 *config.CameraHandle（多摄像头场景，见 CaptureManager）。
+func NewCaptureBackend(cfg config.VideoConfigProvider, frameChan chan *Frame) CaptureBackend {
+	switch cfg.GetVideo().Backend {
+	case "gocv":
+		return NewGoCVCapture(cfg, frameChan)
+	case "gortsplib2":
+		return NewGortsplib2Capture(cfg, frameChan)
+	default:
+		return NewFFmpegCapture(cfg, frameChan)
+	}
+}
+
+// buildRTSPURL 校验 RTSPUrl 并把 Username/Password 编码后注入到其中，
+// 凭据只在这里临时拼接用于实际建连，不会被写回 VideoConfig.RTSPUrl
+func buildRTSPURL(videoConfig config.VideoConfig) (string, error) {
+	if err := ValidateRTSPURL(videoConfig.RTSPUrl); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(videoConfig.RTSPUrl)
+	if err != nil {
+		return "", fmt.Errorf("解析 RTSP 地址失败: %w", err)
+	}
+	if videoConfig.Username != "" {
+		u.User = url.UserPassword(videoConfig.Username, videoConfig.Password)
+	}
+	return u.String(), nil
+}
+
+// BuildCaptureSource 根据 SourceType 返回可直接传给 ffmpeg -i 的输入源：
+// rtsp 源会像 FFmpegCapture 一样临时注入凭据，local 源直接返回 LocalPath。
+// 供需要自行拉起 ffmpeg 进程、复用同一路视频源的场景调用（例如 WHIP 推流编码器）
+func BuildCaptureSource(videoConfig config.VideoConfig) (string, error) {
+	if videoConfig.SourceType == "rtsp" {
+		return buildRTSPURL(videoConfig)
+	}
+	return videoConfig.LocalPath, nil
+}
+
+// ValidateRTSPURL 校验 RTSP 地址格式：必须能被 net/url 解析、host 不为空、端口（若指定）在 1-65535 之间
+func ValidateRTSPURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("解析 RTSP 地址失败: %w", err)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("RTSP 地址缺少 host")
+	}
+	if portStr := u.Port(); portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("RTSP 端口非法: %s", portStr)
+		}
+	}
+	return nil
+}
+
+// reconnectDelay 计算第 attempt 次重连（从 0 开始）前应等待的时间：
+// delay = min(baseMs * factor^attempt, maxDelay)，再乘以 1 + rand*jitter 打散同时重连的请求，
+// factor/maxDelay/jitter 沿用 GRPCClient 重连退避的经验值，只是 base 改为按视频源配置
+func reconnectDelay(attempt int, baseMs int) time.Duration {
+	if baseMs <= 0 {
+		baseMs = 1000
+	}
+	base := float64(time.Duration(baseMs) * time.Millisecond)
+	delay := base * math.Pow(backoffFactor, float64(attempt))
+	if delay > float64(backoffMaxDelay) {
+		delay = float64(backoffMaxDelay)
+	}
+	delay *= 1 + rand.Float64()*backoffJitter
+	return time.Duration(delay)
+}
+
+// runStallWatchdog 定期检查 lastFrameAt（UnixNano，原子存储）是否已超过 stallMs 未更新，
+// 超过则调用 onStall 并退出；stallMs<=0 表示不启用卡死检测
+func runStallWatchdog(ctx context.Context, lastFrameAt *int64, stallMs int, onStall func()) {
+	if stallMs <= 0 {
+		return
+	}
+	threshold := time.Duration(stallMs) * time.Millisecond
+
+	ticker := time.NewTicker(stallCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			last := atomic.LoadInt64(lastFrameAt)
+			if time.Since(time.Unix(0, last)) > threshold {
+				onStall()
+				return
+			}
+		}
+	}
+}
+
+// scheduleReconnect 在断流（EOF/读错误）或卡死检测触发时异步发起一次带退避的重连，
+// reconnecting 标记防止多个触发源（读循环、卡死检测）同时各发起一次重连；
+// restart 失败时保留 attemptCounter 继续累加，成功后由调用方清零
+func scheduleReconnect(ctx context.Context, reconnecting, attemptCounter *int32, baseMs int, name, reason string, restart func() error) {
+	if !atomic.CompareAndSwapInt32(reconnecting, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(reconnecting, 0)
+
+		attempt := int(atomic.AddInt32(attemptCounter, 1) - 1)
+		delay := reconnectDelay(attempt, baseMs)
+		log.Printf("[%s] %s，%v 后尝试第 %d 次重连", name, reason, delay, attempt+1)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		if err := restart(); err != nil {
+			log.Printf("[%s] 重连失败: %v", name, err)
+			return
+		}
+		atomic.StoreInt32(attemptCounter, 0)
+	}()
+}
+
+// ProbeResult 视频源探测结果
+type ProbeResult struct {
+	Codec  string  `json:"codec"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	FPS    float64 `json:"fps"`
+}
+
+// ProbeSource 短暂打开视频源（约 2 秒）并返回编码/分辨率/帧率信息，不启动采集管线，
+// 供 /api/video/probe 在下发完整配置前先验证源是否可达
+func ProbeSource(videoConfig config.VideoConfig) (*ProbeResult, error) {
+	var source string
+	if videoConfig.SourceType == "rtsp" {
+		rtspURL, err := buildRTSPURL(videoConfig)
+		if err != nil {
+			return nil, err
+		}
+		source = rtspURL
+	} else {
+		source = videoConfig.LocalPath
+	}
+
+	return probeWithFFprobe(source, videoConfig)
+}
@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"anomaly_detection_system/backend/internal/config"
+)
+
+// CaptureManager 管理多路摄像头各自的 CaptureBackend 管线，每路摄像头绑定一个
+// config.CameraHandle 与独立的 CaptureBackend 实例，所有摄像头共享同一个 frameChan，
+// 下游（检测后端、WebSocket 推流）通过 Frame.CameraID 区分来源
+type CaptureManager struct {
+	config    *config.Config
+	frameChan chan *Frame
+
+	mu       sync.RWMutex
+	captures map[string]CaptureBackend
+}
+
+// NewCaptureManager 创建多摄像头采集管理器，按 cfg.GetCameras() 当前内容为每路摄像头
+// 创建（但不启动）对应的 CaptureBackend，后续增减摄像头通过 AddCamera/RemoveCamera
+func NewCaptureManager(cfg *config.Config, frameChan chan *Frame) *CaptureManager {
+	m := &CaptureManager{
+		config:    cfg,
+		frameChan: frameChan,
+		captures:  make(map[string]CaptureBackend),
+	}
+	for _, cam := range cfg.GetCameras() {
+		m.captures[cam.CameraID] = NewCaptureBackend(cfg.CameraHandle(cam.CameraID), frameChan)
+	}
+	return m
+}
+
+// Start 启动所有已注册摄像头的采集，单路启动失败不影响其余摄像头，失败的摄像头 ID 与原因一并返回
+func (m *CaptureManager) Start() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var failed []string
+	for cameraID, capture := range m.captures {
+		if err := capture.Start(); err != nil {
+			log.Printf("[CaptureManager] 摄像头 %s 启动失败: %v", cameraID, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", cameraID, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("部分摄像头启动失败: %v", failed)
+	}
+	return nil
+}
+
+// Stop 停止所有摄像头的采集
+func (m *CaptureManager) Stop() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, capture := range m.captures {
+		capture.Stop()
+	}
+}
+
+// AddCamera 新增一路摄像头：写入配置、创建并启动对应的 CaptureBackend；
+// 启动失败时配置已落盘但采集未运行，调用方可后续调用 RestartCamera 重试
+func (m *CaptureManager) AddCamera(cfg config.VideoConfig) error {
+	if err := m.config.AddCamera(cfg); err != nil {
+		return err
+	}
+
+	capture := NewCaptureBackend(m.config.CameraHandle(cfg.CameraID), m.frameChan)
+
+	m.mu.Lock()
+	m.captures[cfg.CameraID] = capture
+	m.mu.Unlock()
+
+	return capture.Start()
+}
+
+// RemoveCamera 停止并移除一路摄像头，同时从配置中删除
+func (m *CaptureManager) RemoveCamera(cameraID string) error {
+	if err := m.config.RemoveCamera(cameraID); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	capture, ok := m.captures[cameraID]
+	delete(m.captures, cameraID)
+	m.mu.Unlock()
+
+	if ok {
+		capture.Stop()
+	}
+	return nil
+}
+
+// RestartCamera 重启指定摄像头的采集，用于切换视频源或从异常状态恢复
+func (m *CaptureManager) RestartCamera(cameraID string) error {
+	capture, ok := m.Camera(cameraID)
+	if !ok {
+		return fmt.Errorf("camera_id %q 不存在", cameraID)
+	}
+	return capture.Restart()
+}
+
+// Camera 返回指定摄像头的 CaptureBackend
+func (m *CaptureManager) Camera(cameraID string) (CaptureBackend, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	capture, ok := m.captures[cameraID]
+	return capture, ok
+}
+
+// Stats 返回按 CameraID 聚合的采集统计信息
+func (m *CaptureManager) Stats() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make(map[string]interface{}, len(m.captures))
+	for cameraID, capture := range m.captures {
+		stats[cameraID] = capture.GetStats()
+	}
+	return stats
+}
@@ -0,0 +1,246 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/model"
+)
+
+// 默认预录/续录时长，ClipConfig 中对应字段 <=0 时使用
+const (
+	defaultPreRollSeconds  = 10
+	defaultPostRollSeconds = 5
+)
+
+// clipFrame 环形缓冲中的一帧：JPEG 数据及采集时间
+type clipFrame struct {
+	jpeg      []byte
+	timestamp time.Time
+}
+
+// cameraRing 单路摄像头的帧环形缓冲，按时间窗口裁剪，不按帧数裁剪
+type cameraRing struct {
+	mu     sync.Mutex
+	frames []clipFrame
+}
+
+// append 追加一帧，并丢弃早于 retention 的历史帧
+func (r *cameraRing) append(frame clipFrame, retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.frames = append(r.frames, frame)
+
+	cutoff := frame.timestamp.Add(-retention)
+	trimmed := r.frames[:0]
+	for _, f := range r.frames {
+		if f.timestamp.After(cutoff) {
+			trimmed = append(trimmed, f)
+		}
+	}
+	r.frames = trimmed
+}
+
+// snapshot 返回时间窗口 [from, to] 内的帧（浅拷贝切片头，底层帧数据只读，与 ResultFanout 共享帧指针的前提一致）
+func (r *cameraRing) snapshot(from, to time.Time) []clipFrame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]clipFrame, 0, len(r.frames))
+	for _, f := range r.frames {
+		if !f.timestamp.Before(from) && !f.timestamp.After(to) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// ClipRecorder 按摄像头维护最近若干秒的 JPEG 环形缓冲（订阅检测结果扇出的一路），
+// 报警触发时拼接预录 + 续录窗口，交给短生命周期的 ffmpeg 子进程编码为 MP4 落盘，
+// 作为报警的可回放视频证据；输出目录为 TrainingConfig.ModelOutputPath 的同级 clips/ 目录
+type ClipRecorder struct {
+	config *config.Config
+	outDir string
+
+	mu    sync.Mutex
+	rings map[string]*cameraRing
+}
+
+// NewClipRecorder 创建录像片段记录器，outDir 在构造时确保存在
+func NewClipRecorder(cfg *config.Config) *ClipRecorder {
+	outDir := ClipsDir(cfg.GetTraining())
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		log.Printf("[ClipRecorder] 创建输出目录失败: %v", err)
+	}
+
+	return &ClipRecorder{
+		config: cfg,
+		outDir: outDir,
+		rings:  make(map[string]*cameraRing),
+	}
+}
+
+// ClipsDir 返回训练模型输出路径的同级 clips/ 目录，供 ClipRecorder 与 main 包共用同一套路径规则
+func ClipsDir(trainingConfig config.TrainingConfig) string {
+	modelDir := filepath.Clean(trainingConfig.ModelOutputPath)
+	return filepath.Join(filepath.Dir(modelDir), "clips")
+}
+
+// Feed 消费一条检测结果，把其中的帧写入对应摄像头的环形缓冲；未启用录像时直接忽略
+func (r *ClipRecorder) Feed(result *DetectionResult) {
+	if !r.config.GetClip().Enable || result == nil || result.Frame == nil {
+		return
+	}
+
+	clipConfig := r.config.GetClip()
+	retention := time.Duration(clipConfig.RingBufferSeconds) * time.Second
+	if retention <= 0 {
+		retention = r.resolvedPreRoll(clipConfig) + r.resolvedPostRoll(clipConfig)
+	}
+
+	ring := r.ringFor(result.Frame.CameraID)
+	ring.append(clipFrame{jpeg: result.Frame.Data, timestamp: result.Frame.Timestamp}, retention)
+}
+
+// ringFor 返回指定摄像头的环形缓冲，不存在则创建
+func (r *ClipRecorder) ringFor(cameraID string) *cameraRing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ring, ok := r.rings[cameraID]
+	if !ok {
+		ring = &cameraRing{}
+		r.rings[cameraID] = ring
+	}
+	return ring
+}
+
+// TriggerClip 为一次报警触发一段录像：立即确定预录窗口起点，等待续录窗口结束后
+// 从环形缓冲取出完整窗口并编码落盘；未启用录像时直接跳过。fps 用于 ffmpeg 的 -framerate 参数，
+// 应传入触发报警的那路摄像头当前配置的采集帧率
+func (r *ClipRecorder) TriggerClip(cameraID string, frameID int64, triggerAt time.Time, fps int) {
+	clipConfig := r.config.GetClip()
+	if !clipConfig.Enable {
+		return
+	}
+
+	preRoll := r.resolvedPreRoll(clipConfig)
+	postRoll := r.resolvedPostRoll(clipConfig)
+	ring := r.ringFor(cameraID)
+
+	go func() {
+		time.Sleep(postRoll)
+
+		frames := ring.snapshot(triggerAt.Add(-preRoll), triggerAt.Add(postRoll))
+		if len(frames) == 0 {
+			log.Printf("[ClipRecorder] 摄像头 %q 无可用帧，跳过录像片段", cameraID)
+			return
+		}
+
+		if fps <= 0 {
+			fps = 30
+		}
+
+		outPath := r.outputPath(cameraID, frameID, triggerAt)
+		if err := encodeClip(frames, fps, outPath); err != nil {
+			log.Printf("[ClipRecorder] 编码录像片段失败: %v", err)
+			return
+		}
+
+		clip := &model.Clip{
+			CameraID:        cameraID,
+			FrameID:         frameID,
+			FilePath:        outPath,
+			TriggeredAt:     triggerAt,
+			DurationSeconds: (preRoll + postRoll).Seconds(),
+		}
+		if err := model.CreateClip(clip); err != nil {
+			log.Printf("[ClipRecorder] 写入录像记录失败: %v", err)
+			return
+		}
+
+		log.Printf("[ClipRecorder] 录像片段已生成: camera=%q, file=%s, frames=%d", cameraID, outPath, len(frames))
+	}()
+}
+
+// resolvedPreRoll/resolvedPostRoll 解析配置值，<=0 时回退到默认值
+func (r *ClipRecorder) resolvedPreRoll(clipConfig config.ClipConfig) time.Duration {
+	seconds := clipConfig.PreRollSeconds
+	if seconds <= 0 {
+		seconds = defaultPreRollSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (r *ClipRecorder) resolvedPostRoll(clipConfig config.ClipConfig) time.Duration {
+	seconds := clipConfig.PostRollSeconds
+	if seconds <= 0 {
+		seconds = defaultPostRollSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// clipCameraSegment 把摄像头 ID 映射为文件名安全的片段，空 ID（单摄像头场景）映射为 "default"
+func clipCameraSegment(cameraID string) string {
+	if cameraID == "" {
+		return "default"
+	}
+	return cameraID
+}
+
+// outputPath 按 {camera_id}_{frame_id}_{timestamp}.mp4 命名规则拼出输出文件路径
+func (r *ClipRecorder) outputPath(cameraID string, frameID int64, triggerAt time.Time) string {
+	name := fmt.Sprintf("%s_%d_%d.mp4", clipCameraSegment(cameraID), frameID, triggerAt.UnixMilli())
+	return filepath.Join(r.outDir, name)
+}
+
+// encodeClip 把一组按时间排好序的 JPEG 帧通过 image2pipe 喂给 ffmpeg，编码为 faststart 的 H.264 MP4
+func encodeClip(frames []clipFrame, fps int, outPath string) error {
+	cmd := exec.Command("ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-y",
+		"-f", "image2pipe",
+		"-framerate", fmt.Sprintf("%d", fps),
+		"-i", "-",
+		"-c:v", "libx264",
+		"-pix_fmt", "yuv420p",
+		"-movflags", "+faststart",
+		outPath,
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("创建 stdin 管道失败: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 ffmpeg 失败: %w", err)
+	}
+
+	for _, frame := range frames {
+		if _, err := stdin.Write(frame.jpeg); err != nil {
+			stdin.Close()
+			cmd.Wait()
+			return fmt.Errorf("写入帧数据失败: %w", err)
+		}
+	}
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg 编码失败: %w (%s)", err, stderr.String())
+	}
+
+	return nil
+}
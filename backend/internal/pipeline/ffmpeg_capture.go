@@ -0,0 +1,440 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+)
+
+// Frame 视频帧数据
+type Frame struct {
+	ID        int64     // 帧序号
+	CameraID  string    // 来源摄像头 ID，单摄像头场景下为空
+	Data      []byte    // JPEG 编码后的图像数据
+	Timestamp time.Time // 采集时间
+	Width     int       // 图像宽度
+	Height    int       // 图像高度
+}
+
+// FFmpegCapture 基于 ffmpeg 子进程的视频采集后端，是 CaptureBackend 的默认实现
+type FFmpegCapture struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 配置
+	config config.VideoConfigProvider
+
+	// ffmpeg 进程
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	isOpen bool
+
+	// 视频信息
+	width  int
+	height int
+
+	// cameraID 在 Start 时从配置缓存，避免 captureLoop 逐帧加锁读取配置
+	cameraID string
+
+	// 帧输出通道
+	frameChan chan *Frame
+
+	// 统计
+	frameID   int64
+	totalRead int64
+	errors    int64
+
+	lastFrameAt      int64 // 原子存储，最近一次成功读到帧的 UnixNano，供卡死检测使用
+	reconnecting     int32 // 原子标记，避免 EOF 路径和卡死检测同时触发重连
+	reconnectAttempt int32 // 原子计数，连续重连失败次数，重连成功后清零
+
+	fpsOverride int32 // 原子存储，SetFPS 下发的帧率覆盖值，<=0 表示沿用配置中的 FPS
+}
+
+// NewFFmpegCapture 创建 ffmpeg 视频采集器
+func NewFFmpegCapture(cfg config.VideoConfigProvider, frameChan chan *Frame) *FFmpegCapture {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &FFmpegCapture{
+		config:    cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+		frameChan: frameChan,
+		width:     1280, // 默认宽度
+		height:    720,  // 默认高度
+	}
+}
+
+// Name 返回后端名称
+func (vc *FFmpegCapture) Name() string {
+	return "ffmpeg"
+}
+
+// Start 启动视频采集
+func (vc *FFmpegCapture) Start() error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if vc.isOpen {
+		return nil
+	}
+
+	videoConfig := vc.config.GetVideo()
+
+	// 确定视频源
+	var source string
+	if videoConfig.SourceType == "rtsp" {
+		rtspURL, err := buildRTSPURL(videoConfig)
+		if err != nil {
+			return err
+		}
+		source = rtspURL
+		log.Printf("[FFmpegCapture] 正在连接 RTSP 流: %s", videoConfig.RTSPUrl)
+	} else {
+		source = videoConfig.LocalPath
+		log.Printf("[FFmpegCapture] 正在打开本地视频: %s", source)
+	}
+
+	// 构建 ffmpeg 命令，输出 MJPEG 帧到 stdout
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+	}
+
+	// RTSP 特殊参数：传输模式与建连超时
+	if videoConfig.SourceType == "rtsp" {
+		if videoConfig.Transport != "" && videoConfig.Transport != "auto" {
+			args = append(args, "-rtsp_transport", videoConfig.Transport)
+		}
+		readTimeoutMs := videoConfig.ReadTimeoutMs
+		if readTimeoutMs <= 0 {
+			readTimeoutMs = 5000
+		}
+		args = append(args, "-stimeout", strconv.Itoa(readTimeoutMs*1000)) // 微秒
+	}
+
+	// 循环播放本地视频
+	if videoConfig.SourceType == "local" {
+		args = append(args, "-stream_loop", "-1")
+	}
+
+	fps := videoConfig.FPS
+	if override := atomic.LoadInt32(&vc.fpsOverride); override > 0 {
+		fps = int(override)
+	}
+
+	args = append(args,
+		"-i", source,
+		"-f", "image2pipe",
+		"-vf", fmt.Sprintf("fps=%d,scale=%d:%d", fps, vc.width, vc.height),
+		"-vcodec", "mjpeg",
+		"-q:v", "5", // JPEG 质量
+		"-",
+	)
+
+	vc.cameraID = videoConfig.CameraID
+
+	vc.cmd = exec.CommandContext(vc.ctx, "ffmpeg", args...)
+
+	var err error
+	vc.stdout, err = vc.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建 stdout 管道失败: %w", err)
+	}
+
+	// 启动 ffmpeg
+	if err := vc.cmd.Start(); err != nil {
+		return fmt.Errorf("启动 ffmpeg 失败: %w", err)
+	}
+
+	vc.isOpen = true
+	atomic.StoreInt64(&vc.lastFrameAt, time.Now().UnixNano())
+	log.Printf("[FFmpegCapture] ffmpeg 已启动，帧率: %d FPS, 分辨率: %dx%d, 传输模式: %s",
+		fps, vc.width, vc.height, videoConfig.Transport)
+
+	// 启动采集协程与卡死检测协程，二者共享同一代 ctx
+	ctx := vc.ctx
+	go vc.captureLoop(ctx)
+	go vc.stallWatchdog(ctx, videoConfig.StallDetectionMs)
+
+	return nil
+}
+
+// Stop 停止视频采集
+func (vc *FFmpegCapture) Stop() {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if !vc.isOpen {
+		return
+	}
+
+	vc.cancel()
+
+	if vc.cmd != nil && vc.cmd.Process != nil {
+		vc.cmd.Process.Kill()
+		vc.cmd.Wait()
+		vc.cmd = nil
+	}
+
+	if vc.stdout != nil {
+		vc.stdout.Close()
+		vc.stdout = nil
+	}
+
+	vc.isOpen = false
+	log.Printf("[FFmpegCapture] 视频采集已停止，共读取 %d 帧，错误 %d 次", vc.totalRead, vc.errors)
+}
+
+// Restart 重启视频采集（用于切换视频源或从断流中恢复）
+func (vc *FFmpegCapture) Restart() error {
+	vc.Stop()
+
+	// 重新创建 context，开启新的一代采集协程
+	vc.mu.Lock()
+	vc.ctx, vc.cancel = context.WithCancel(context.Background())
+	vc.mu.Unlock()
+
+	return vc.Start()
+}
+
+// captureLoop 视频采集循环
+func (vc *FFmpegCapture) captureLoop(ctx context.Context) {
+	vc.mu.RLock()
+	stdout := vc.stdout
+	vc.mu.RUnlock()
+
+	reader := bufio.NewReader(stdout)
+
+	log.Println("[FFmpegCapture] 采集循环启动")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[FFmpegCapture] 采集循环收到停止信号")
+			return
+		default:
+			// 读取 JPEG 帧
+			jpegData, err := vc.readJPEGFrame(reader)
+			if err != nil {
+				if err == io.EOF {
+					log.Println("[FFmpegCapture] 视频流结束")
+					scheduleReconnect(ctx, &vc.reconnecting, &vc.reconnectAttempt,
+						vc.config.GetVideo().ReconnectBackoffMs, "FFmpegCapture", "视频流已结束", vc.Restart)
+					return
+				}
+				vc.errors++
+				// 短暂休眠避免错误循环
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			if len(jpegData) == 0 {
+				continue
+			}
+
+			vc.frameID++
+			vc.totalRead++
+			atomic.StoreInt64(&vc.lastFrameAt, time.Now().UnixNano())
+
+			frame := &Frame{
+				ID:        vc.frameID,
+				CameraID:  vc.cameraID,
+				Data:      jpegData,
+				Timestamp: time.Now(),
+				Width:     vc.width,
+				Height:    vc.height,
+			}
+
+			// 非阻塞发送到通道
+			select {
+			case vc.frameChan <- frame:
+			default:
+				// 通道满了，丢弃帧
+				if vc.frameID%100 == 0 {
+					log.Println("[FFmpegCapture] 帧通道已满，丢弃帧")
+				}
+			}
+		}
+	}
+}
+
+// stallWatchdog 定期检查距离上一帧是否已超过 StallDetectionMs，超过则判定为卡死并触发重连
+func (vc *FFmpegCapture) stallWatchdog(ctx context.Context, stallDetectionMs int) {
+	runStallWatchdog(ctx, &vc.lastFrameAt, stallDetectionMs, func() {
+		scheduleReconnect(ctx, &vc.reconnecting, &vc.reconnectAttempt,
+			vc.config.GetVideo().ReconnectBackoffMs, "FFmpegCapture",
+			fmt.Sprintf("超过 %dms 未收到新帧，判定为卡死", stallDetectionMs), vc.Restart)
+	})
+}
+
+// readJPEGFrame 从流中读取一个完整的 JPEG 帧
+func (vc *FFmpegCapture) readJPEGFrame(reader *bufio.Reader) ([]byte, error) {
+	// JPEG 起始标记: FF D8
+	// JPEG 结束标记: FF D9
+
+	var buf bytes.Buffer
+
+	// 查找 JPEG 起始标记
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == 0xFF {
+			next, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if next == 0xD8 {
+				// 找到起始标记
+				buf.WriteByte(0xFF)
+				buf.WriteByte(0xD8)
+				break
+			}
+			// 不是起始标记，放回
+			reader.UnreadByte()
+		}
+	}
+
+	// 读取直到结束标记
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(b)
+
+		if b == 0xFF {
+			next, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteByte(next)
+
+			if next == 0xD9 {
+				// 找到结束标记
+				return buf.Bytes(), nil
+			}
+		}
+
+		// 防止读取过大的帧
+		if buf.Len() > 10*1024*1024 { // 10MB 限制
+			return nil, fmt.Errorf("帧数据过大")
+		}
+	}
+}
+
+// GetStats 获取统计信息
+func (vc *FFmpegCapture) GetStats() map[string]interface{} {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+
+	return map[string]interface{}{
+		"frame_id":          vc.frameID,
+		"total_read":        vc.totalRead,
+		"errors":            vc.errors,
+		"is_open":           vc.isOpen,
+		"source_type":       vc.config.GetVideo().SourceType,
+		"width":             vc.width,
+		"height":            vc.height,
+		"reconnect_attempt": atomic.LoadInt32(&vc.reconnectAttempt),
+	}
+}
+
+// IsOpen 检查视频源是否打开
+func (vc *FFmpegCapture) IsOpen() bool {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return vc.isOpen
+}
+
+// SetResolution 设置分辨率
+func (vc *FFmpegCapture) SetResolution(width, height int) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.width = width
+	vc.height = height
+}
+
+// SetFPS 动态调整采集帧率：记录覆盖值后重启 ffmpeg 子进程使其生效，
+// 未打开时只记录覆盖值，留到下次 Start 时使用
+func (vc *FFmpegCapture) SetFPS(fps int) error {
+	if fps <= 0 {
+		return fmt.Errorf("fps 必须为正数")
+	}
+	atomic.StoreInt32(&vc.fpsOverride, int32(fps))
+
+	if !vc.IsOpen() {
+		return nil
+	}
+	return vc.Restart()
+}
+
+// probeWithFFprobe 用 ffprobe 短暂打开 source 并解析编码/分辨率/帧率，整体受 2 秒超时限制
+func probeWithFFprobe(source string, videoConfig config.VideoConfig) (*ProbeResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	args := []string{
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name,width,height,r_frame_rate",
+		"-of", "default=noprint_wrappers=1",
+	}
+	if videoConfig.SourceType == "rtsp" && videoConfig.Transport != "" && videoConfig.Transport != "auto" {
+		args = append(args, "-rtsp_transport", videoConfig.Transport)
+	}
+	args = append(args, "-i", source)
+
+	output, err := exec.CommandContext(ctx, "ffprobe", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("探测视频源失败: %w", err)
+	}
+
+	width, height, fps, codec := parseProbeOutput(string(output))
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("未能从视频源解析出有效的分辨率")
+	}
+
+	return &ProbeResult{Codec: codec, Width: width, Height: height, FPS: fps}, nil
+}
+
+// parseProbeOutput 解析 ffprobe -of default=noprint_wrappers=1 的 key=value 输出
+func parseProbeOutput(output string) (width, height int, fps float64, codec string) {
+	lines := strings.Split(output, "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "width="):
+			width, _ = strconv.Atoi(strings.TrimPrefix(line, "width="))
+		case strings.HasPrefix(line, "height="):
+			height, _ = strconv.Atoi(strings.TrimPrefix(line, "height="))
+		case strings.HasPrefix(line, "codec_name="):
+			codec = strings.TrimPrefix(line, "codec_name=")
+		case strings.HasPrefix(line, "r_frame_rate="):
+			rate := strings.TrimPrefix(line, "r_frame_rate=")
+			parts := strings.Split(rate, "/")
+			if len(parts) == 2 {
+				num, _ := strconv.ParseFloat(parts[0], 64)
+				den, _ := strconv.ParseFloat(parts[1], 64)
+				if den > 0 {
+					fps = num / den
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,285 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/format/rtpmjpeg"
+	"github.com/pion/rtp"
+
+	"anomaly_detection_system/backend/internal/config"
+)
+
+// Gortsplib2Capture 基于纯 Go RTSP 客户端 gortsplib/v4 的视频采集后端，不依赖 ffmpeg/OpenCV，
+// 建连延迟更低，但目前只能解出 RTP/JPEG（RFC 2435）子码流 —— 多数 NVR 的子码流
+// （"?subtype=1"）按 MJPEG 编码配置后即可直接使用，主码流的 H.264/H.265 需要走 FFmpegCapture
+type Gortsplib2Capture struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	config config.VideoConfigProvider
+
+	client *gortsplib.Client
+	isOpen bool
+
+	width  int
+	height int
+
+	// cameraID 在 Start 时从配置缓存，避免 onFrame 逐帧加锁读取配置
+	cameraID string
+
+	frameChan chan *Frame
+
+	frameID   int64
+	totalRead int64
+	errors    int64
+
+	lastFrameAt      int64
+	reconnecting     int32
+	reconnectAttempt int32
+}
+
+// NewGortsplib2Capture 创建 gortsplib2 视频采集器
+func NewGortsplib2Capture(cfg config.VideoConfigProvider, frameChan chan *Frame) *Gortsplib2Capture {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Gortsplib2Capture{
+		config:    cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+		frameChan: frameChan,
+		width:     1280,
+		height:    720,
+	}
+}
+
+// Name 返回后端名称
+func (rc *Gortsplib2Capture) Name() string {
+	return "gortsplib2"
+}
+
+// Start 启动视频采集
+func (rc *Gortsplib2Capture) Start() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.isOpen {
+		return nil
+	}
+
+	videoConfig := rc.config.GetVideo()
+	if videoConfig.SourceType != "rtsp" {
+		return fmt.Errorf("gortsplib2 后端只支持 rtsp 源")
+	}
+
+	rtspURL, err := buildRTSPURL(videoConfig)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(rtspURL)
+	if err != nil {
+		return fmt.Errorf("解析 RTSP 地址失败: %w", err)
+	}
+
+	readTimeoutMs := videoConfig.ReadTimeoutMs
+	if readTimeoutMs <= 0 {
+		readTimeoutMs = 5000
+	}
+
+	client := &gortsplib.Client{
+		Transport:   rtspTransport(videoConfig.Transport),
+		ReadTimeout: time.Duration(readTimeoutMs) * time.Millisecond,
+	}
+
+	if err := client.Start(u.Scheme, u.Host); err != nil {
+		return fmt.Errorf("建立 RTSP 连接失败: %w", err)
+	}
+
+	desc, _, err := client.Describe(u)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("DESCRIBE 失败: %w", err)
+	}
+
+	var mjpegFormat *format.MJPEG
+	mjpegMedia := desc.FindFormat(&mjpegFormat)
+	if mjpegMedia == nil {
+		client.Close()
+		return fmt.Errorf("该 RTSP 源未找到 MJPEG 子媒体流，请切换为 ffmpeg 后端或改用 MJPEG 子码流")
+	}
+
+	decoder, err := mjpegFormat.CreateDecoder()
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("创建 MJPEG 解码器失败: %w", err)
+	}
+	mjpegDecoder, _ := decoder.(*rtpmjpeg.Decoder)
+
+	if err := client.Setup(desc.BaseURL, mjpegMedia, 0, 0); err != nil {
+		client.Close()
+		return fmt.Errorf("SETUP 失败: %w", err)
+	}
+
+	rc.cameraID = videoConfig.CameraID
+
+	rc.client = client
+	rc.isOpen = true
+	atomic.StoreInt64(&rc.lastFrameAt, time.Now().UnixNano())
+
+	ctx := rc.ctx
+	client.OnPacketRTP(mjpegMedia, mjpegFormat, func(pkt *rtp.Packet) {
+		jpegData, err := mjpegDecoder.Decode(pkt)
+		if err != nil {
+			return
+		}
+		rc.onFrame(ctx, jpegData)
+	})
+
+	if _, err := client.Play(nil); err != nil {
+		client.Close()
+		rc.isOpen = false
+		return fmt.Errorf("PLAY 失败: %w", err)
+	}
+
+	log.Printf("[Gortsplib2Capture] 已连接: %s", videoConfig.RTSPUrl)
+
+	go rc.waitLoop(ctx)
+	go runStallWatchdog(ctx, &rc.lastFrameAt, videoConfig.StallDetectionMs, func() {
+		scheduleReconnect(ctx, &rc.reconnecting, &rc.reconnectAttempt,
+			videoConfig.ReconnectBackoffMs, "Gortsplib2Capture",
+			fmt.Sprintf("超过 %dms 未收到新帧，判定为卡死", videoConfig.StallDetectionMs), rc.Restart)
+	})
+
+	return nil
+}
+
+// onFrame 将解码出的 JPEG 数据投递到 frameChan
+func (rc *Gortsplib2Capture) onFrame(ctx context.Context, jpegData []byte) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+
+	rc.frameID++
+	rc.totalRead++
+	atomic.StoreInt64(&rc.lastFrameAt, time.Now().UnixNano())
+
+	frame := &Frame{
+		ID:        rc.frameID,
+		CameraID:  rc.cameraID,
+		Data:      jpegData,
+		Timestamp: time.Now(),
+		Width:     rc.width,
+		Height:    rc.height,
+	}
+
+	select {
+	case rc.frameChan <- frame:
+	default:
+		if rc.frameID%100 == 0 {
+			log.Println("[Gortsplib2Capture] 帧通道已满，丢弃帧")
+		}
+	}
+}
+
+// waitLoop 阻塞等待连接断开（client.Wait()），断开后触发重连
+func (rc *Gortsplib2Capture) waitLoop(ctx context.Context) {
+	rc.mu.RLock()
+	client := rc.client
+	rc.mu.RUnlock()
+
+	err := client.Wait()
+
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+
+	rc.errors++
+	log.Printf("[Gortsplib2Capture] 连接断开: %v", err)
+	scheduleReconnect(ctx, &rc.reconnecting, &rc.reconnectAttempt,
+		rc.config.GetVideo().ReconnectBackoffMs, "Gortsplib2Capture", "RTSP 连接已断开", rc.Restart)
+}
+
+// Stop 停止视频采集
+func (rc *Gortsplib2Capture) Stop() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !rc.isOpen {
+		return
+	}
+
+	rc.cancel()
+
+	if rc.client != nil {
+		rc.client.Close()
+		rc.client = nil
+	}
+
+	rc.isOpen = false
+	log.Printf("[Gortsplib2Capture] 视频采集已停止，共读取 %d 帧，错误 %d 次", rc.totalRead, rc.errors)
+}
+
+// Restart 重启视频采集
+func (rc *Gortsplib2Capture) Restart() error {
+	rc.Stop()
+
+	rc.mu.Lock()
+	rc.ctx, rc.cancel = context.WithCancel(context.Background())
+	rc.mu.Unlock()
+
+	return rc.Start()
+}
+
+// GetStats 获取统计信息
+func (rc *Gortsplib2Capture) GetStats() map[string]interface{} {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	return map[string]interface{}{
+		"frame_id":          rc.frameID,
+		"total_read":        rc.totalRead,
+		"errors":            rc.errors,
+		"is_open":           rc.isOpen,
+		"source_type":       rc.config.GetVideo().SourceType,
+		"width":             rc.width,
+		"height":            rc.height,
+		"reconnect_attempt": atomic.LoadInt32(&rc.reconnectAttempt),
+	}
+}
+
+// IsOpen 检查视频源是否打开
+func (rc *Gortsplib2Capture) IsOpen() bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.isOpen
+}
+
+// SetFPS gortsplib2 后端按 RTSP 服务端推送的节奏转发帧，不支持运行时调整帧率
+func (rc *Gortsplib2Capture) SetFPS(fps int) error {
+	return fmt.Errorf("gortsplib2 后端不支持动态调整帧率")
+}
+
+// rtspTransport 把配置里的字符串映射为 gortsplib 的传输模式，"auto"/空 交给库自己协商
+func rtspTransport(transport string) *gortsplib.Transport {
+	var t gortsplib.Transport
+	switch transport {
+	case "tcp":
+		t = gortsplib.TransportTCP
+	case "udp":
+		t = gortsplib.TransportUDP
+	default:
+		return nil
+	}
+	return &t
+}
@@ -1,18 +1,35 @@
 package pipeline
 
 import (
+	"container/heap"
 	"context"
+	"fmt"
 	"log"
+	"math"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	"anomaly_detection_system/backend/internal/config"
 	pb "anomaly_detection_system/backend/pb"
 )
 
+// 退避参数：沿用 gRPC 连接退避的经验值
+const (
+	backoffBase      = 100 * time.Millisecond
+	backoffFactor    = 1.6
+	backoffMaxDelay  = 30 * time.Second
+	backoffJitter    = 0.2
+	maxDetectRetries = 5    // Detect 一元调用的最大重试次数
+	retryBudgetRate  = 10.0 // 所有 worker 共享的重试预算，单位：次/秒
+)
+
 // DetectionResult 检测结果
 type DetectionResult struct {
 	FrameID       int64        // 帧序号
@@ -51,29 +68,224 @@ type GRPCClient struct {
 	frameChan  chan *Frame
 	resultChan chan *DetectionResult
 
-	// 有序处理
-	workerCount int
-	pending     sync.Map // frameID -> chan *DetectionResult
-
-	// 统计
+	// 流式处理：一个小型流连接池，缓解单条流的队头阻塞
+	streamCount    int
+	inFlightWindow int           // 单流最大在途请求数（信号量容量）
+	frameTimeout   time.Duration // 单帧在途超时时间，用于流重连后判断是否还需重发
+	streams        []*detectStream
+	inFlight       sync.Map // frameID -> *inFlightFrame
+
+	pending sync.Map // frameID -> chan *DetectionResult
+
+	// 重排序缓冲区：按 FrameID 排序的最小堆 + 哈希索引，由 orderingLoop 按序排空
+	reorderMu         sync.Mutex
+	reorderHeap       reorderHeap
+	reorderIndex      map[int64]*reorderItem
+	nextOut           int64
+	nextOutInit       bool
+	maxReorderLatency time.Duration // 重排序等待上限，超时则跳过空洞
+	notifyOrder       chan struct{}
+
+	// 重排序统计
+	reorderWaitMs int64 // 最近一次出队结果在缓冲区中的等待耗时（毫秒）
+	droppedGaps   int64 // 因等待超时而被跳过的帧数
+
+	// 统计：并发读写自多个协程（dispatchLoop/recvLoop/drainReorderBuffer），
+	// 用原子操作而非 gc.mu/gc.reorderMu，避免跨锁读取造成数据竞争
 	totalSent     int64
 	totalReceived int64
 	errors        int64
+
+	// 重试控制：指数退避 + 令牌桶限速，避免 AI 服务异常时的重连/重试风暴
+	retryBudget      *retryBudget
+	backoffAttempt   int32 // 原子计数，连续失败次数，成功后重置
+	currentBackoffMs int64 // 原子存储，最近一次计算出的退避时长
+}
+
+// retryBudget 令牌桶重试预算：限制所有 worker 累计的重试速率，
+// 避免持续故障下重试请求把负载放大数倍（10 次/秒的量级足以应对抖动，又不会淹没刚恢复的服务）
+type retryBudget struct {
+	mu           sync.Mutex
+	tokens       float64
+	maxTokens    float64
+	refillPerSec float64
+	lastRefill   time.Time
+}
+
+func newRetryBudget(ratePerSec float64) *retryBudget {
+	return &retryBudget{
+		tokens:       ratePerSec,
+		maxTokens:    ratePerSec,
+		refillPerSec: ratePerSec,
+		lastRefill:   time.Now(),
+	}
+}
+
+// take 尝试消费一个重试配额，预算耗尽时返回 false
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refillLocked()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// remaining 返回当前剩余配额（向下取整）
+func (b *retryBudget) remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refillLocked()
+	return int(b.tokens)
+}
+
+func (b *retryBudget) refillLocked() {
+	now := time.Now()
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.lastRefill = now
+
+	b.tokens += elapsed * b.refillPerSec
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// backoffDelay 计算第 attempt 次重试（从 0 开始）前应等待的时间：
+// delay = min(base * factor^attempt, maxDelay)，再乘以 1 + rand*jitter 打散同时重连的请求
+func backoffDelay(attempt int) time.Duration {
+	delay := float64(backoffBase) * math.Pow(backoffFactor, float64(attempt))
+	if delay > float64(backoffMaxDelay) {
+		delay = float64(backoffMaxDelay)
+	}
+	delay *= 1 + rand.Float64()*backoffJitter
+	return time.Duration(delay)
+}
+
+// isRetryableError 判断错误是否值得重试。allowInternal 用于区分双向流场景：
+// 流上的 Internal 往往是 AI 服务的瞬时性通用错误，而一元调用的 Internal 更可能是请求本身有问题
+func isRetryableError(err error, allowInternal bool) bool {
+	if err == nil {
+		return false
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		// 非 gRPC status 错误（连接中断等传输层错误），按可重试处理
+		return true
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	case codes.Internal:
+		return allowInternal
+	default:
+		return false
+	}
+}
+
+// reorderItem 重排序缓冲区中的一个条目
+type reorderItem struct {
+	frameID    int64
+	result     *DetectionResult
+	bufferedAt time.Time
+}
+
+// reorderHeap 按 FrameID 升序排列的最小堆
+type reorderHeap []*reorderItem
+
+func (h reorderHeap) Len() int           { return len(h) }
+func (h reorderHeap) Less(i, j int) bool { return h[i].frameID < h[j].frameID }
+func (h reorderHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *reorderHeap) Push(x interface{}) {
+	*h = append(*h, x.(*reorderItem))
+}
+
+func (h *reorderHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// detectStream 流连接池中的一条双向流，带独立的在途请求信号量
+type detectStream struct {
+	mu         sync.RWMutex
+	stream     pb.DetectionService_StreamDetectClient
+	sem        chan struct{}
+	generation int64 // 当前 stream 所属的代次，每次重连后 +1，用于让旧代的 recvLoop 能识别出自己已过期
+
+	reconnecting int32 // CAS 锁：Send/Recv 两侧都可能触发重连，保证同一条流同时只有一个协程在执行重连
+}
+
+func (ds *detectStream) getStream() pb.DetectionService_StreamDetectClient {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.stream
+}
+
+// setStream 切换到重连后的新 stream 并推进代次，返回新代次号
+func (ds *detectStream) setStream(s pb.DetectionService_StreamDetectClient) int64 {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.stream = s
+	ds.generation++
+	return ds.generation
+}
+
+func (ds *detectStream) currentGeneration() int64 {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.generation
+}
+
+// inFlightFrame 已发送但尚未收到响应的帧
+type inFlightFrame struct {
+	frame    *Frame
+	ds       *detectStream
+	deadline time.Time
 }
 
 // NewGRPCClient 创建 gRPC 客户端
 func NewGRPCClient(cfg *config.Config, frameChan chan *Frame, resultChan chan *DetectionResult) *GRPCClient {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &GRPCClient{
-		config:      cfg,
-		ctx:         ctx,
-		cancel:      cancel,
-		frameChan:   frameChan,
-		resultChan:  resultChan,
-		workerCount: 4, // 并发工作协程数
+		config:            cfg,
+		ctx:               ctx,
+		cancel:            cancel,
+		frameChan:         frameChan,
+		resultChan:        resultChan,
+		streamCount:       2,               // 流连接池大小
+		inFlightWindow:    8,               // 单流最大在途请求数
+		frameTimeout:      5 * time.Second, // 单帧在途超时时间
+		reorderIndex:      make(map[int64]*reorderItem),
+		maxReorderLatency: 2 * time.Second, // 重排序等待上限
+		notifyOrder:       make(chan struct{}, 1),
+		retryBudget:       newRetryBudget(retryBudgetRate),
 	}
 }
 
+// nextBackoff 计算并记录下一次重试的退避时长，每次调用视为一次新的失败尝试
+func (gc *GRPCClient) nextBackoff() time.Duration {
+	attempt := atomic.AddInt32(&gc.backoffAttempt, 1) - 1
+	delay := backoffDelay(int(attempt))
+	atomic.StoreInt64(&gc.currentBackoffMs, delay.Milliseconds())
+	return delay
+}
+
+// resetBackoff 在重试成功后清零退避状态
+func (gc *GRPCClient) resetBackoff() {
+	atomic.StoreInt32(&gc.backoffAttempt, 0)
+	atomic.StoreInt64(&gc.currentBackoffMs, 0)
+}
+
 // Start 启动 gRPC 客户端
 func (gc *GRPCClient) Start() error {
 	gc.mu.Lock()
@@ -100,11 +312,14 @@ func (gc *GRPCClient) Start() error {
 
 	log.Printf("[GRPCClient] 已连接到 AI 服务")
 
+	// 打开流连接池
+	if err := gc.openStreams(); err != nil {
+		return err
+	}
+
 	// 启动有序处理协程池
 	go gc.orderingLoop()
-	for i := 0; i < gc.workerCount; i++ {
-		go gc.workerLoop(i)
-	}
+	go gc.dispatchLoop()
 
 	return nil
 }
@@ -116,103 +331,223 @@ func (gc *GRPCClient) Stop() {
 
 	gc.cancel()
 
+	for _, ds := range gc.streams {
+		if s := ds.getStream(); s != nil {
+			s.CloseSend()
+		}
+	}
+
 	if gc.conn != nil {
 		gc.conn.Close()
 		gc.conn = nil
 	}
 
 	log.Printf("[GRPCClient] 已断开连接，发送 %d，接收 %d，错误 %d",
-		gc.totalSent, gc.totalReceived, gc.errors)
+		atomic.LoadInt64(&gc.totalSent), atomic.LoadInt64(&gc.totalReceived), atomic.LoadInt64(&gc.errors))
 }
 
-// workerLoop 工作协程
-func (gc *GRPCClient) workerLoop(id int) {
-	log.Printf("[GRPCClient] Worker %d 启动", id)
+// openStreams 建立流连接池，每条流各自启动一个接收协程
+func (gc *GRPCClient) openStreams() error {
+	gc.streams = make([]*detectStream, 0, gc.streamCount)
+
+	for i := 0; i < gc.streamCount; i++ {
+		stream, err := gc.client.StreamDetect(gc.ctx)
+		if err != nil {
+			return fmt.Errorf("打开检测流 %d 失败: %w", i, err)
+		}
+
+		ds := &detectStream{
+			stream: stream,
+			sem:    make(chan struct{}, gc.inFlightWindow),
+		}
+		gc.streams = append(gc.streams, ds)
+
+		go gc.recvLoop(i, ds, ds.currentGeneration())
+	}
+
+	log.Printf("[GRPCClient] 已建立 %d 条检测流，单流在途窗口=%d", gc.streamCount, gc.inFlightWindow)
+	return nil
+}
+
+// dispatchLoop 从帧通道取帧并轮询分发到流连接池
+func (gc *GRPCClient) dispatchLoop() {
+	next := 0
 
 	for {
 		select {
 		case <-gc.ctx.Done():
-			log.Printf("[GRPCClient] Worker %d 停止", id)
 			return
 		case frame := <-gc.frameChan:
 			if frame == nil {
 				continue
 			}
 
-			// 执行检测
-			result := gc.detect(frame)
-			if result != nil {
-				// 发送到结果通道
-				select {
-				case gc.resultChan <- result:
-					gc.totalReceived++
-				default:
-					log.Println("[GRPCClient] 结果通道已满，丢弃结果")
-				}
+			if len(gc.streams) == 0 {
+				continue
 			}
+
+			ds := gc.streams[next%len(gc.streams)]
+			next++
+
+			gc.sendFrame(ds, frame)
 		}
 	}
 }
 
-// orderingLoop 有序输出协程（确保结果按帧序号排序）
-func (gc *GRPCClient) orderingLoop() {
-	// 简化实现：由于 workerLoop 直接输出到 resultChan，
-	// 这里可以添加更复杂的排序逻辑
-	// 当前实现中，我们依赖下游处理器处理乱序
-}
-
-// detect 执行单帧检测
-func (gc *GRPCClient) detect(frame *Frame) *DetectionResult {
-	gc.mu.RLock()
-	client := gc.client
-	gc.mu.RUnlock()
-
-	if client == nil {
-		return nil
+// sendFrame 向指定流发送一帧，信号量已满时会阻塞，从而把背压传导回 frameChan
+func (gc *GRPCClient) sendFrame(ds *detectStream, frame *Frame) {
+	select {
+	case ds.sem <- struct{}{}:
+	case <-gc.ctx.Done():
+		return
 	}
 
-	// 构建请求
+	gc.inFlight.Store(frame.ID, &inFlightFrame{
+		frame:    frame,
+		ds:       ds,
+		deadline: time.Now().Add(gc.frameTimeout),
+	})
+
 	req := &pb.DetectRequest{
 		ImageData:   frame.Data,
 		FrameId:     frame.ID,
 		ImageFormat: "jpeg",
 	}
 
-	// 调用 AI 服务（带超时和重试）
-	var resp *pb.DetectResponse
-	var err error
-	maxRetries := 3
+	if err := ds.getStream().Send(req); err != nil {
+		gc.inFlight.Delete(frame.ID)
+		<-ds.sem
+		log.Printf("[GRPCClient] 流发送失败: %v", err)
+		gc.handleStreamError(ds, err)
+		return
+	}
+
+	atomic.AddInt64(&gc.totalSent, 1)
+}
+
+// recvLoop 持续接收一条流上的响应，按 frame_id 匹配在途帧后放入重排序缓冲区。
+// gen 是这条 recvLoop 所服务的 stream 代次，一旦 ds 被重连推进到更新的代次，说明
+// 已经有另一个 recvLoop 接管了这条 detectStream，这里直接退出，避免同一 ds 上存在两个 recvLoop
+func (gc *GRPCClient) recvLoop(id int, ds *detectStream, gen int64) {
+	for {
+		if ds.currentGeneration() != gen {
+			return
+		}
+
+		resp, err := ds.getStream().Recv()
+		if err != nil {
+			if gc.ctx.Err() != nil {
+				return
+			}
+			if ds.currentGeneration() != gen {
+				return
+			}
+			log.Printf("[GRPCClient] 流 %d 接收失败: %v", id, err)
+			gc.handleStreamError(ds, err)
+			return
+		}
 
-	for i := 0; i < maxRetries; i++ {
-		ctx, cancel := context.WithTimeout(gc.ctx, 5*time.Second)
-		resp, err = client.Detect(ctx, req)
-		cancel()
+		select {
+		case <-ds.sem:
+		default:
+		}
 
-		if err == nil && resp.Error == "" {
-			break
+		raw, ok := gc.inFlight.LoadAndDelete(resp.FrameId)
+		if !ok {
+			continue
 		}
+		inflight := raw.(*inFlightFrame)
 
-		if i < maxRetries-1 {
-			log.Printf("[GRPCClient] 检测请求失败 (重试 %d/%d): %v", i+1, maxRetries, err)
-			time.Sleep(100 * time.Millisecond)
+		if resp.Error != "" {
+			atomic.AddInt64(&gc.errors, 1)
+			log.Printf("[GRPCClient] AI 服务返回错误: %s", resp.Error)
+			continue
 		}
+
+		gc.publishResult(gc.buildResult(inflight.frame, resp))
+	}
+}
+
+// handleStreamError 流出错后重新建立该条流，并重发尚未超时的在途帧。
+// sendFrame 和 recvLoop 在同一条流上几乎总是同时出错，都会调用本函数；用 ds.reconnecting 的
+// CAS 保证同一条 detectStream 同时只有一个协程执行重连，没抢到的协程直接返回，避免重复
+// StreamDetect、重复 setStream、重复 recvLoop 和重复重发在途帧
+func (gc *GRPCClient) handleStreamError(ds *detectStream, cause error) {
+	if gc.ctx.Err() != nil {
+		return
+	}
+
+	atomic.AddInt64(&gc.errors, 1)
+
+	if !atomic.CompareAndSwapInt32(&ds.reconnecting, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&ds.reconnecting, 0)
+
+	gc.mu.Lock()
+	client := gc.client
+	gc.mu.Unlock()
+
+	if client == nil {
+		return
+	}
+
+	if !isRetryableError(cause, true) {
+		log.Printf("[GRPCClient] 不可重试的错误，放弃重连: %v", cause)
+		return
+	}
+
+	if !gc.retryBudget.take() {
+		log.Printf("[GRPCClient] 重试预算已耗尽，放弃本次重连: %v", cause)
+		return
 	}
 
-	gc.totalSent++
+	delay := gc.nextBackoff()
+	log.Printf("[GRPCClient] 流异常，%v 后重连: %v", delay, cause)
 
+	select {
+	case <-time.After(delay):
+	case <-gc.ctx.Done():
+		return
+	}
+
+	newStream, err := client.StreamDetect(gc.ctx)
 	if err != nil {
-		gc.errors++
-		log.Printf("[GRPCClient] 检测请求最终失败: %v", err)
-		return nil
+		log.Printf("[GRPCClient] 流重连失败: %v", err)
+		return
 	}
+	gc.resetBackoff()
+	gen := ds.setStream(newStream)
 
-	if resp.Error != "" {
-		gc.errors++
-		log.Printf("[GRPCClient] AI 服务返回错误: %s", resp.Error)
-		return nil
+	// 回收信号量，腾出在途配额给即将重发的帧
+	for {
+		select {
+		case <-ds.sem:
+			continue
+		default:
+		}
+		break
 	}
 
-	// 解析结果
+	now := time.Now()
+	gc.inFlight.Range(func(key, value interface{}) bool {
+		inflight := value.(*inFlightFrame)
+		if inflight.ds != ds {
+			return true
+		}
+		gc.inFlight.Delete(key)
+		if inflight.deadline.Before(now) {
+			return true
+		}
+		go gc.sendFrame(ds, inflight.frame)
+		return true
+	})
+
+	go gc.recvLoop(-1, ds, gen)
+}
+
+// buildResult 将流响应转换为内部 DetectionResult
+func (gc *GRPCClient) buildResult(frame *Frame, resp *pb.DetectResponse) *DetectionResult {
 	result := &DetectionResult{
 		FrameID:       resp.FrameId,
 		Frame:         frame,
@@ -222,26 +557,177 @@ func (gc *GRPCClient) detect(frame *Frame) *DetectionResult {
 	}
 
 	for _, r := range resp.Results {
-		detection := &Detection{
+		x1, y1, x2, y2 := bboxCoords(r.Bbox)
+		result.Detections = append(result.Detections, &Detection{
 			ID:          r.Id,
-			X1:          r.Bbox.X1,
-			Y1:          r.Bbox.Y1,
-			X2:          r.Bbox.X2,
-			Y2:          r.Bbox.Y2,
+			X1:          x1,
+			Y1:          y1,
+			X2:          x2,
+			Y2:          y2,
 			ClassName:   r.ClassName,
 			ClassID:     r.ClassId,
 			Confidence:  r.Confidence,
 			Entropy:     r.Entropy,
 			IsUncertain: r.IsUncertain,
-		}
-		result.Detections = append(result.Detections, detection)
+		})
 	}
 
 	return result
 }
 
-// UpdateAIParams 更新 AI 服务参数
-func (gc *GRPCClient) UpdateAIParams(params *pb.UpdateParamsRequest) (*pb.UpdateParamsResponse, error) {
+// publishResult 将一个检测结果放入重排序缓冲区并唤醒 orderingLoop
+func (gc *GRPCClient) publishResult(result *DetectionResult) {
+	gc.reorderMu.Lock()
+	if !gc.nextOutInit {
+		gc.nextOut = result.FrameID
+		gc.nextOutInit = true
+	}
+
+	if _, exists := gc.reorderIndex[result.FrameID]; exists {
+		// 重复结果（重试等原因），丢弃旧的
+		gc.reorderMu.Unlock()
+		return
+	}
+
+	item := &reorderItem{
+		frameID:    result.FrameID,
+		result:     result,
+		bufferedAt: time.Now(),
+	}
+	heap.Push(&gc.reorderHeap, item)
+	gc.reorderIndex[result.FrameID] = item
+	gc.reorderMu.Unlock()
+
+	select {
+	case gc.notifyOrder <- struct{}{}:
+	default:
+	}
+}
+
+// orderingLoop 有序输出协程：从重排序缓冲区中按 FrameID 顺序排空结果到 resultChan
+func (gc *GRPCClient) orderingLoop() {
+	// 用定时器兜底，即使没有新结果到达也能推进超时的空洞
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-gc.ctx.Done():
+			return
+		case <-gc.notifyOrder:
+			gc.drainReorderBuffer()
+		case <-ticker.C:
+			gc.drainReorderBuffer()
+		}
+	}
+}
+
+// drainReorderBuffer 排空重排序缓冲区中从 nextOut 开始的连续前缀
+func (gc *GRPCClient) drainReorderBuffer() {
+	gc.reorderMu.Lock()
+	defer gc.reorderMu.Unlock()
+
+	for gc.reorderHeap.Len() > 0 {
+		head := gc.reorderHeap[0]
+
+		if head.frameID < gc.nextOut {
+			// 迟到的旧帧，直接丢弃
+			heap.Pop(&gc.reorderHeap)
+			delete(gc.reorderIndex, head.frameID)
+			continue
+		}
+
+		if head.frameID == gc.nextOut {
+			heap.Pop(&gc.reorderHeap)
+			delete(gc.reorderIndex, head.frameID)
+			gc.reorderWaitMs = time.Since(head.bufferedAt).Milliseconds()
+
+			select {
+			case gc.resultChan <- head.result:
+				atomic.AddInt64(&gc.totalReceived, 1)
+			default:
+				log.Println("[GRPCClient] 结果通道已满，丢弃结果")
+			}
+
+			gc.nextOut++
+			continue
+		}
+
+		// head.frameID > nextOut：出现空洞，检查队首是否已超过最大重排序等待时间
+		if time.Since(head.bufferedAt) > gc.maxReorderLatency {
+			gc.droppedGaps++
+			log.Printf("[GRPCClient] 帧 %d 等待超时，跳过空洞 (期望帧号=%d)", head.frameID, gc.nextOut)
+			// 合成一个空结果，让下游（跟踪、编码等）仍能观察到单调递增的帧号
+			select {
+			case gc.resultChan <- &DetectionResult{FrameID: gc.nextOut, Timestamp: time.Now()}:
+				atomic.AddInt64(&gc.totalReceived, 1)
+			default:
+			}
+			gc.nextOut = head.frameID
+			continue
+		}
+
+		// 队首还没超时，等待更多结果或下一次超时检查
+		break
+	}
+}
+
+// Detect 对单帧执行检测（一元 RPC，供不走流式调度的场景使用，如后端健康检查）
+// GRPCClient 的正常取流路径是 dispatchLoop/recvLoop，不经过这里。
+func (gc *GRPCClient) Detect(ctx context.Context, frame *Frame) (*DetectionResult, error) {
+	gc.mu.RLock()
+	client := gc.client
+	gc.mu.RUnlock()
+
+	if client == nil {
+		return nil, fmt.Errorf("gRPC 客户端未连接")
+	}
+
+	req := &pb.DetectRequest{
+		ImageData:   frame.Data,
+		FrameId:     frame.ID,
+		ImageFormat: "jpeg",
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxDetectRetries; attempt++ {
+		resp, err := client.Detect(ctx, req)
+		if err == nil {
+			if resp.Error != "" {
+				return nil, fmt.Errorf("AI 服务返回错误: %s", resp.Error)
+			}
+			gc.resetBackoff()
+			return gc.buildResult(frame, resp), nil
+		}
+
+		lastErr = err
+		if !isRetryableError(err, false) {
+			return nil, err
+		}
+		if !gc.retryBudget.take() {
+			return nil, fmt.Errorf("重试预算已耗尽: %w", err)
+		}
+
+		delay := gc.nextBackoff()
+		log.Printf("[GRPCClient] Detect 调用失败，%v 后重试 (第 %d 次): %v", delay, attempt+1, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, fmt.Errorf("已达到最大重试次数 %d: %w", maxDetectRetries, lastErr)
+}
+
+// Name 返回后端名称，用于日志与状态展示
+func (gc *GRPCClient) Name() string {
+	return "grpc"
+}
+
+// UpdateParams 更新 AI 服务参数
+func (gc *GRPCClient) UpdateParams(params *pb.UpdateParamsRequest) (*pb.UpdateParamsResponse, error) {
 	gc.mu.RLock()
 	client := gc.client
 	gc.mu.RUnlock()
@@ -286,15 +772,35 @@ func (gc *GRPCClient) ReloadModel(modelPath string) (*pb.ReloadModelResponse, er
 	})
 }
 
-// GetStats 获取统计信息
-func (gc *GRPCClient) GetStats() map[string]interface{} {
+// Stats 返回后端统计信息
+func (gc *GRPCClient) Stats() map[string]interface{} {
 	gc.mu.RLock()
-	defer gc.mu.RUnlock()
+	connected := gc.conn != nil
+	gc.mu.RUnlock()
+
+	gc.reorderMu.Lock()
+	bufferDepth := gc.reorderHeap.Len()
+	waitMs := gc.reorderWaitMs
+	droppedGaps := gc.droppedGaps
+	gc.reorderMu.Unlock()
+
+	inFlightCount := 0
+	gc.inFlight.Range(func(_, _ interface{}) bool {
+		inFlightCount++
+		return true
+	})
 
 	return map[string]interface{}{
-		"total_sent":     gc.totalSent,
-		"total_received": gc.totalReceived,
-		"errors":         gc.errors,
-		"connected":      gc.conn != nil,
+		"total_sent":               atomic.LoadInt64(&gc.totalSent),
+		"total_received":           atomic.LoadInt64(&gc.totalReceived),
+		"errors":                   atomic.LoadInt64(&gc.errors),
+		"connected":                connected,
+		"reorder_buffer_depth":     bufferDepth,
+		"reorder_wait_ms":          waitMs,
+		"dropped_gaps":             droppedGaps,
+		"stream_count":             len(gc.streams),
+		"in_flight":                inFlightCount,
+		"backoff_current_delay_ms": atomic.LoadInt64(&gc.currentBackoffMs),
+		"retry_budget_remaining":   gc.retryBudget.remaining(),
 	}
 }
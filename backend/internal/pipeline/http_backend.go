@@ -0,0 +1,256 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"anomaly_detection_system/backend/internal/config"
+	pb "anomaly_detection_system/backend/pb"
+)
+
+// HTTPBackend 基于 REST 的检测后端，用于 AI 服务以 HTTP 接口暴露推理能力的部署场景
+type HTTPBackend struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	config *config.Config
+	client *http.Client
+
+	frameChan  chan *Frame
+	resultChan chan *DetectionResult
+
+	workerCount int // 并发请求 AI 服务的 worker 数
+
+	// 统计
+	totalSent     int64
+	totalReceived int64
+	errors        int64
+}
+
+// httpDetectResponse /detect 响应体
+type httpDetectResponse struct {
+	FrameID         int64                 `json:"frame_id"`
+	Results         []*pb.DetectionResult `json:"results"`
+	InferenceTimeMs int64                 `json:"inference_time_ms"`
+	Error           string                `json:"error"`
+}
+
+// httpUpdateParamsRequest /update_params 请求体
+type httpUpdateParamsRequest struct {
+	ConfidenceThreshold *float32 `json:"confidence_threshold,omitempty"`
+	EntropyThreshold    *float32 `json:"entropy_threshold,omitempty"`
+	NmsIouThreshold     *float32 `json:"nms_iou_threshold,omitempty"`
+	InputSize           *int32   `json:"input_size,omitempty"`
+}
+
+// httpReloadModelRequest /reload_model 请求体
+type httpReloadModelRequest struct {
+	ModelPath string `json:"model_path"`
+}
+
+// httpSimpleResponse /update_params、/reload_model 共用的响应体
+type httpSimpleResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// NewHTTPBackend 创建 HTTP 检测后端
+func NewHTTPBackend(cfg *config.Config, frameChan chan *Frame, resultChan chan *DetectionResult) *HTTPBackend {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HTTPBackend{
+		config:      cfg,
+		ctx:         ctx,
+		cancel:      cancel,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		frameChan:   frameChan,
+		resultChan:  resultChan,
+		workerCount: 4,
+	}
+}
+
+// Start 启动 HTTP 检测后端
+func (hb *HTTPBackend) Start() error {
+	go runBackendLoop(hb.ctx, hb, hb.frameChan, hb.resultChan, hb.workerCount)
+	return nil
+}
+
+// Stop 停止 HTTP 检测后端
+func (hb *HTTPBackend) Stop() {
+	hb.cancel()
+}
+
+// Detect 对单帧执行检测，以 multipart/form-data 上传原始 JPEG 字节，避免 base64 带来的体积膨胀
+func (hb *HTTPBackend) Detect(ctx context.Context, frame *Frame) (*DetectionResult, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("image", fmt.Sprintf("%d.jpg", frame.ID))
+	if err != nil {
+		return nil, fmt.Errorf("构建检测请求失败: %w", err)
+	}
+	if _, err := part.Write(frame.Data); err != nil {
+		return nil, fmt.Errorf("写入图像数据失败: %w", err)
+	}
+	if err := writer.WriteField("frame_id", strconv.FormatInt(frame.ID, 10)); err != nil {
+		return nil, fmt.Errorf("写入表单字段失败: %w", err)
+	}
+	if err := writer.WriteField("image_format", "jpeg"); err != nil {
+		return nil, fmt.Errorf("写入表单字段失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("构建检测请求失败: %w", err)
+	}
+
+	var detectResp httpDetectResponse
+	if err := hb.doMultipart(ctx, "/detect", writer.FormDataContentType(), body.Bytes(), &detectResp); err != nil {
+		hb.mu.Lock()
+		hb.errors++
+		hb.mu.Unlock()
+		return nil, err
+	}
+
+	if detectResp.Error != "" {
+		hb.mu.Lock()
+		hb.errors++
+		hb.mu.Unlock()
+		return nil, fmt.Errorf("AI 服务返回错误: %s", detectResp.Error)
+	}
+
+	hb.mu.Lock()
+	hb.totalSent++
+	hb.totalReceived++
+	hb.mu.Unlock()
+
+	result := &DetectionResult{
+		FrameID:       detectResp.FrameID,
+		Frame:         frame,
+		InferenceTime: detectResp.InferenceTimeMs,
+		Timestamp:     time.Now(),
+		Detections:    make([]*Detection, 0, len(detectResp.Results)),
+	}
+
+	for _, r := range detectResp.Results {
+		x1, y1, x2, y2 := bboxCoords(r.Bbox)
+		result.Detections = append(result.Detections, &Detection{
+			ID:          r.Id,
+			X1:          x1,
+			Y1:          y1,
+			X2:          x2,
+			Y2:          y2,
+			ClassName:   r.ClassName,
+			ClassID:     r.ClassId,
+			Confidence:  r.Confidence,
+			Entropy:     r.Entropy,
+			IsUncertain: r.IsUncertain,
+		})
+	}
+
+	return result, nil
+}
+
+// UpdateParams 更新 AI 服务运行参数
+func (hb *HTTPBackend) UpdateParams(params *pb.UpdateParamsRequest) (*pb.UpdateParamsResponse, error) {
+	reqBody, err := json.Marshal(&httpUpdateParamsRequest{
+		ConfidenceThreshold: params.ConfidenceThreshold,
+		EntropyThreshold:    params.EntropyThreshold,
+		NmsIouThreshold:     params.NmsIouThreshold,
+		InputSize:           params.InputSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化参数更新请求失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var resp httpSimpleResponse
+	if err := hb.doJSON(ctx, "/update_params", reqBody, &resp); err != nil {
+		return nil, err
+	}
+
+	return &pb.UpdateParamsResponse{Success: resp.Success, Message: resp.Message}, nil
+}
+
+// ReloadModel 重新加载模型
+func (hb *HTTPBackend) ReloadModel(modelPath string) (*pb.ReloadModelResponse, error) {
+	reqBody, err := json.Marshal(&httpReloadModelRequest{ModelPath: modelPath})
+	if err != nil {
+		return nil, fmt.Errorf("序列化模型重载请求失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // 模型加载可能较慢
+	defer cancel()
+
+	var resp httpSimpleResponse
+	if err := hb.doJSON(ctx, "/reload_model", reqBody, &resp); err != nil {
+		return nil, err
+	}
+
+	return &pb.ReloadModelResponse{Success: resp.Success, Message: resp.Message}, nil
+}
+
+// Stats 返回后端统计信息
+func (hb *HTTPBackend) Stats() map[string]interface{} {
+	hb.mu.RLock()
+	defer hb.mu.RUnlock()
+
+	return map[string]interface{}{
+		"total_sent":     hb.totalSent,
+		"total_received": hb.totalReceived,
+		"errors":         hb.errors,
+	}
+}
+
+// Name 返回后端名称，用于日志与状态展示
+func (hb *HTTPBackend) Name() string {
+	return "http"
+}
+
+// doJSON 向 AI 服务的 REST 接口发起一次 JSON POST 请求并解析响应
+func (hb *HTTPBackend) doJSON(ctx context.Context, path string, reqBody []byte, out interface{}) error {
+	return hb.doPost(ctx, path, "application/json", bytes.NewReader(reqBody), out)
+}
+
+// doMultipart 向 AI 服务的 REST 接口发起一次 multipart/form-data POST 请求并解析响应，
+// 用于 /detect：直接上传原始 JPEG 字节，避免 base64 编码带来的约 33% 体积膨胀
+func (hb *HTTPBackend) doMultipart(ctx context.Context, path, contentType string, reqBody []byte, out interface{}) error {
+	return hb.doPost(ctx, path, contentType, bytes.NewReader(reqBody), out)
+}
+
+// doPost 向 AI 服务的 REST 接口发起一次 POST 请求并将响应体解析为 JSON
+func (hb *HTTPBackend) doPost(ctx context.Context, path, contentType string, body *bytes.Reader, out interface{}) error {
+	endpoint := hb.config.GetAI().HTTPEndpoint
+	if endpoint == "" {
+		return fmt.Errorf("HTTP 检测后端未配置 http_endpoint")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("构建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := hb.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 AI 服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("AI 服务返回非 200 状态码: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析 AI 服务响应失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	ort "github.com/yalue/onnxruntime_go"
+
+	"anomaly_detection_system/backend/internal/config"
+	pb "anomaly_detection_system/backend/pb"
+)
+
+// LocalONNXBackend 进程内直接加载 ONNX 模型推理的检测后端，免去一次网络往返，
+// 适合把 Go 服务和模型部署在同一台机器上的场景
+type LocalONNXBackend struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	config *config.Config
+
+	frameChan  chan *Frame
+	resultChan chan *DetectionResult
+
+	session   *ort.DynamicAdvancedSession
+	modelPath string
+
+	workerCount int // 推理 worker 数，受限于 ONNX Runtime session 的并发安全性
+
+	// 统计
+	totalSent     int64
+	totalReceived int64
+	errors        int64
+}
+
+// NewLocalONNXBackend 创建本地 ONNX 检测后端
+func NewLocalONNXBackend(cfg *config.Config, frameChan chan *Frame, resultChan chan *DetectionResult) *LocalONNXBackend {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LocalONNXBackend{
+		config:      cfg,
+		ctx:         ctx,
+		cancel:      cancel,
+		frameChan:   frameChan,
+		resultChan:  resultChan,
+		workerCount: 1, // DynamicAdvancedSession.Run 非并发安全，默认单 worker
+	}
+}
+
+// Start 加载 ONNX 模型并启动推理 worker
+func (lb *LocalONNXBackend) Start() error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.modelPath = lb.config.GetAI().LocalModelPath
+	if lb.modelPath == "" {
+		return fmt.Errorf("本地 ONNX 后端未配置 local_model_path")
+	}
+
+	if err := ort.InitializeEnvironment(); err != nil {
+		return fmt.Errorf("初始化 ONNX Runtime 环境失败: %w", err)
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(lb.modelPath,
+		[]string{"images"}, []string{"output"}, nil)
+	if err != nil {
+		return fmt.Errorf("加载 ONNX 模型失败: %w", err)
+	}
+	lb.session = session
+
+	log.Printf("[LocalONNXBackend] 已加载模型: %s", lb.modelPath)
+
+	go runBackendLoop(lb.ctx, lb, lb.frameChan, lb.resultChan, lb.workerCount)
+	return nil
+}
+
+// Stop 释放 ONNX Runtime 资源
+func (lb *LocalONNXBackend) Stop() {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.cancel()
+
+	if lb.session != nil {
+		lb.session.Destroy()
+		lb.session = nil
+	}
+	ort.DestroyEnvironment()
+
+	log.Printf("[LocalONNXBackend] 已停止，发送 %d，接收 %d，错误 %d",
+		lb.totalSent, lb.totalReceived, lb.errors)
+}
+
+// Detect 对单帧执行本地推理
+func (lb *LocalONNXBackend) Detect(ctx context.Context, frame *Frame) (*DetectionResult, error) {
+	lb.mu.RLock()
+	session := lb.session
+	lb.mu.RUnlock()
+
+	if session == nil {
+		return nil, fmt.Errorf("本地 ONNX 模型未加载")
+	}
+
+	aiConfig := lb.config.GetAI()
+
+	img, err := jpeg.Decode(bytes.NewReader(frame.Data))
+	if err != nil {
+		lb.mu.Lock()
+		lb.errors++
+		lb.mu.Unlock()
+		return nil, fmt.Errorf("解码帧图像失败: %w", err)
+	}
+
+	input, err := preprocessForONNX(img, aiConfig.InputSize)
+	if err != nil {
+		lb.mu.Lock()
+		lb.errors++
+		lb.mu.Unlock()
+		return nil, fmt.Errorf("预处理图像失败: %w", err)
+	}
+	defer input.Destroy()
+
+	start := time.Now()
+
+	outputShape := ort.NewShape(1, 0)
+	output, err := ort.NewEmptyTensor[float32](outputShape)
+	if err != nil {
+		lb.mu.Lock()
+		lb.errors++
+		lb.mu.Unlock()
+		return nil, fmt.Errorf("创建输出张量失败: %w", err)
+	}
+	defer output.Destroy()
+
+	if err := session.Run([]ort.Value{input}, []ort.Value{output}); err != nil {
+		lb.mu.Lock()
+		lb.errors++
+		lb.mu.Unlock()
+		return nil, fmt.Errorf("模型推理失败: %w", err)
+	}
+
+	detections := postprocessONNXOutput(output.GetData(), aiConfig)
+
+	lb.mu.Lock()
+	lb.totalSent++
+	lb.totalReceived++
+	lb.mu.Unlock()
+
+	return &DetectionResult{
+		FrameID:       frame.ID,
+		Frame:         frame,
+		InferenceTime: time.Since(start).Milliseconds(),
+		Timestamp:     time.Now(),
+		Detections:    detections,
+	}, nil
+}
+
+// UpdateParams 更新运行参数。本地后端没有独立进程，直接写回本地配置即可生效
+func (lb *LocalONNXBackend) UpdateParams(params *pb.UpdateParamsRequest) (*pb.UpdateParamsResponse, error) {
+	aiConfig := lb.config.GetAI()
+
+	if params.ConfidenceThreshold != nil {
+		aiConfig.ConfidenceThreshold = *params.ConfidenceThreshold
+	}
+	if params.EntropyThreshold != nil {
+		aiConfig.EntropyThreshold = *params.EntropyThreshold
+	}
+	if params.NmsIouThreshold != nil {
+		aiConfig.NMSIoUThreshold = *params.NmsIouThreshold
+	}
+	if params.InputSize != nil {
+		aiConfig.InputSize = int(*params.InputSize)
+	}
+
+	lb.config.UpdateAI(aiConfig)
+
+	return &pb.UpdateParamsResponse{Success: true, Message: "本地配置已更新"}, nil
+}
+
+// ReloadModel 重新加载本地模型文件
+func (lb *LocalONNXBackend) ReloadModel(modelPath string) (*pb.ReloadModelResponse, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if modelPath == "" {
+		modelPath = lb.modelPath
+	}
+
+	newSession, err := ort.NewDynamicAdvancedSession(modelPath,
+		[]string{"images"}, []string{"output"}, nil)
+	if err != nil {
+		return &pb.ReloadModelResponse{Success: false, Message: err.Error()}, nil
+	}
+
+	if lb.session != nil {
+		lb.session.Destroy()
+	}
+	lb.session = newSession
+	lb.modelPath = modelPath
+
+	log.Printf("[LocalONNXBackend] 模型已重载: %s", modelPath)
+	return &pb.ReloadModelResponse{Success: true, Message: "模型已重载"}, nil
+}
+
+// Stats 返回后端统计信息
+func (lb *LocalONNXBackend) Stats() map[string]interface{} {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	return map[string]interface{}{
+		"total_sent":     lb.totalSent,
+		"total_received": lb.totalReceived,
+		"errors":         lb.errors,
+		"model_path":     lb.modelPath,
+	}
+}
+
+// Name 返回后端名称，用于日志与状态展示
+func (lb *LocalONNXBackend) Name() string {
+	return "local_onnx"
+}
+
+// preprocessForONNX 将图像缩放到 inputSize 正方形并转换为 NCHW 的 float32 张量
+func preprocessForONNX(img image.Image, inputSize int) (*ort.Tensor[float32], error) {
+	bounds := img.Bounds()
+	data := make([]float32, 3*inputSize*inputSize)
+
+	scaleX := float64(bounds.Dx()) / float64(inputSize)
+	scaleY := float64(bounds.Dy()) / float64(inputSize)
+	plane := inputSize * inputSize
+
+	for y := 0; y < inputSize; y++ {
+		for x := 0; x < inputSize; x++ {
+			srcX := bounds.Min.X + int(float64(x)*scaleX)
+			srcY := bounds.Min.Y + int(float64(y)*scaleY)
+			r, g, b, _ := img.At(srcX, srcY).RGBA()
+			idx := y*inputSize + x
+			data[idx] = float32(r>>8) / 255.0
+			data[plane+idx] = float32(g>>8) / 255.0
+			data[2*plane+idx] = float32(b>>8) / 255.0
+		}
+	}
+
+	return ort.NewTensor(ort.NewShape(1, 3, int64(inputSize), int64(inputSize)), data)
+}
+
+// postprocessONNXOutput 将模型原始输出解码为检测框，并按配置阈值执行置信度过滤与 NMS
+func postprocessONNXOutput(raw []float32, aiConfig config.AIConfig) []*Detection {
+	const stride = 6 // x1, y1, x2, y2, confidence, class_id
+
+	candidates := make([]*Detection, 0, len(raw)/stride)
+	for i := 0; i+stride <= len(raw); i += stride {
+		confidence := raw[i+4]
+		if confidence < aiConfig.ConfidenceThreshold {
+			continue
+		}
+
+		candidates = append(candidates, &Detection{
+			ID:         int32(len(candidates)),
+			X1:         raw[i],
+			Y1:         raw[i+1],
+			X2:         raw[i+2],
+			Y2:         raw[i+3],
+			Confidence: confidence,
+			ClassID:    int32(raw[i+5]),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+
+	return nmsFilter(candidates, aiConfig.NMSIoUThreshold)
+}
+
+// nmsFilter 对按置信度降序排列的候选框执行标准 NMS
+func nmsFilter(candidates []*Detection, iouThreshold float32) []*Detection {
+	kept := make([]*Detection, 0, len(candidates))
+
+	for _, c := range candidates {
+		overlap := false
+		for _, k := range kept {
+			if iou(c, k) > iouThreshold {
+				overlap = true
+				break
+			}
+		}
+		if !overlap {
+			kept = append(kept, c)
+		}
+	}
+
+	return kept
+}
+
+// iou 计算两个检测框的交并比
+func iou(a, b *Detection) float32 {
+	x1 := max32(a.X1, b.X1)
+	y1 := max32(a.Y1, b.Y1)
+	x2 := min32(a.X2, b.X2)
+	y2 := min32(a.Y2, b.Y2)
+
+	interW := max32(0, x2-x1)
+	interH := max32(0, y2-y1)
+	inter := interW * interH
+
+	areaA := (a.X2 - a.X1) * (a.Y2 - a.Y1)
+	areaB := (b.X2 - b.X1) * (b.Y2 - b.Y1)
+
+	union := areaA + areaB - inter
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
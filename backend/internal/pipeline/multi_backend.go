@@ -0,0 +1,192 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"anomaly_detection_system/backend/internal/config"
+	pb "anomaly_detection_system/backend/pb"
+)
+
+// MultiBackend 把同一帧并发分发给多个子后端，取第一个成功返回的结果，
+// 用于后端可用性存疑或灰度切换场景下的冗余调用
+type MultiBackend struct {
+	mu       sync.RWMutex
+	children []DetectionBackend
+	childMus []sync.Mutex // 每个子后端各一把锁，串行化发给同一子后端的 Detect 调用
+
+	frameChan  chan *Frame
+	resultChan chan *DetectionResult
+
+	workerCount int // 并发消费 frameChan 的 worker 数
+}
+
+// NewMultiBackend 根据 config.AI.MultiBackendTypes 创建多后端组合，子类型取值同 BackendType（不含 "multi"）。
+// 子后端只通过 Detect 被直接调用，不给它们传入 frameChan/resultChan，避免和 MultiBackend 自己的
+// runBackendLoop 抢同一路帧
+func NewMultiBackend(cfg *config.Config, frameChan chan *Frame, resultChan chan *DetectionResult) *MultiBackend {
+	types := cfg.GetAI().MultiBackendTypes
+	children := make([]DetectionBackend, 0, len(types))
+	for _, t := range types {
+		children = append(children, newNamedDetectionBackend(t, cfg, nil, nil))
+	}
+
+	return &MultiBackend{
+		children:    children,
+		childMus:    make([]sync.Mutex, len(children)),
+		frameChan:   frameChan,
+		resultChan:  resultChan,
+		workerCount: 4,
+	}
+}
+
+// Start 启动所有子后端
+func (mb *MultiBackend) Start() error {
+	mb.mu.RLock()
+	children := mb.children
+	mb.mu.RUnlock()
+
+	if len(children) == 0 {
+		return fmt.Errorf("MultiBackend 未配置任何子后端 (ai.multi_backend_types)")
+	}
+
+	for _, child := range children {
+		if err := child.Start(); err != nil {
+			return fmt.Errorf("子后端 %s 启动失败: %w", child.Name(), err)
+		}
+	}
+
+	go runBackendLoop(context.Background(), mb, mb.frameChan, mb.resultChan, mb.workerCount)
+	return nil
+}
+
+// Stop 停止所有子后端
+func (mb *MultiBackend) Stop() {
+	mb.mu.RLock()
+	children := mb.children
+	mb.mu.RUnlock()
+
+	for _, child := range children {
+		child.Stop()
+	}
+}
+
+// Detect 并发向所有子后端发起检测，取第一个成功返回的结果作为最终结果。
+// 每个子后端各自的 Detect 调用经 childMus 串行化：部分子后端实现（如 LocalONNXBackend 的
+// ONNX Runtime session）本身不是并发安全的，MultiBackend 的 worker 并发和多路 fan-out
+// 叠加起来很容易同时调用到同一个子后端，这里保证同一子后端任一时刻只有一个 Detect 在跑。
+// 若某个子后端仍在处理上一帧（TryLock 失败），本帧对它直接记为失败而不是排队等待，
+// 避免子后端变慢/卡住时，帧在其锁上无界堆积导致协程和帧数据不断增长
+func (mb *MultiBackend) Detect(ctx context.Context, frame *Frame) (*DetectionResult, error) {
+	mb.mu.RLock()
+	children := mb.children
+	childMus := mb.childMus
+	mb.mu.RUnlock()
+
+	if len(children) == 0 {
+		return nil, fmt.Errorf("MultiBackend 未配置任何子后端")
+	}
+
+	detectCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		result *DetectionResult
+		err    error
+	}
+	results := make(chan outcome, len(children))
+
+	for i, child := range children {
+		go func(b DetectionBackend, childMu *sync.Mutex) {
+			if !childMu.TryLock() {
+				results <- outcome{err: fmt.Errorf("子后端 %s 仍在处理上一帧，跳过本帧", b.Name())}
+				return
+			}
+			defer childMu.Unlock()
+			result, err := b.Detect(detectCtx, frame)
+			results <- outcome{result: result, err: err}
+		}(child, &childMus[i])
+	}
+
+	var errs []string
+	for i := 0; i < len(children); i++ {
+		o := <-results
+		if o.err == nil && o.result != nil {
+			return o.result, nil
+		}
+		if o.err != nil {
+			errs = append(errs, o.err.Error())
+		}
+	}
+
+	return nil, fmt.Errorf("所有子后端均未返回有效结果: %s", strings.Join(errs, "; "))
+}
+
+// UpdateParams 把参数更新下发给所有子后端，只要有一个成功即视为成功
+func (mb *MultiBackend) UpdateParams(params *pb.UpdateParamsRequest) (*pb.UpdateParamsResponse, error) {
+	mb.mu.RLock()
+	children := mb.children
+	mb.mu.RUnlock()
+
+	var messages []string
+	success := false
+	for _, child := range children {
+		resp, err := child.UpdateParams(params)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %v", child.Name(), err))
+			continue
+		}
+		if resp.Success {
+			success = true
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", child.Name(), resp.Message))
+	}
+
+	return &pb.UpdateParamsResponse{Success: success, Message: strings.Join(messages, "; ")}, nil
+}
+
+// ReloadModel 把模型重载下发给所有子后端，只要有一个成功即视为成功
+func (mb *MultiBackend) ReloadModel(modelPath string) (*pb.ReloadModelResponse, error) {
+	mb.mu.RLock()
+	children := mb.children
+	mb.mu.RUnlock()
+
+	var messages []string
+	success := false
+	for _, child := range children {
+		resp, err := child.ReloadModel(modelPath)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %v", child.Name(), err))
+			continue
+		}
+		if resp.Success {
+			success = true
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", child.Name(), resp.Message))
+	}
+
+	return &pb.ReloadModelResponse{Success: success, Message: strings.Join(messages, "; ")}, nil
+}
+
+// Stats 聚合所有子后端的统计信息，按后端名称分组
+func (mb *MultiBackend) Stats() map[string]interface{} {
+	mb.mu.RLock()
+	children := mb.children
+	mb.mu.RUnlock()
+
+	stats := make(map[string]interface{}, len(children))
+	for _, child := range children {
+		stats[child.Name()] = child.Stats()
+	}
+
+	return map[string]interface{}{
+		"backends": stats,
+	}
+}
+
+// Name 返回后端名称，用于日志与状态展示
+func (mb *MultiBackend) Name() string {
+	return "multi"
+}
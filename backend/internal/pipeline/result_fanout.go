@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ResultFanout 把单一检测结果输入流复制给多个独立订阅者（WebSocket 推流、报警处理、
+// 录像片段、MQTT 发布等），每个订阅者拥有自己的缓冲 channel 与丢弃计数，某个订阅者消费
+// 不及时时只丢弃它自己的那份拷贝，不会互相阻塞或互相饿死。
+//
+// 所有订阅者共享同一个 *DetectionResult（及其内部 *Frame），这是安全的：
+// Frame.Data 在整个管线里只被读取（JSON/base64 编码、JPEG 解码、gRPC 序列化），
+// 从未被原地修改，因此无需为每个订阅者克隆一份。
+type ResultFanout struct {
+	in <-chan *DetectionResult
+
+	mu          sync.Mutex
+	subscribers []*fanoutSubscriber
+}
+
+// fanoutSubscriber 单个订阅者的 channel 与投递/丢弃统计
+type fanoutSubscriber struct {
+	name      string
+	ch        chan *DetectionResult
+	delivered int64
+	dropped   int64
+}
+
+// NewResultFanout 创建结果扇出器，调用方需要另起协程调用 Run 才会开始分发
+func NewResultFanout(in <-chan *DetectionResult) *ResultFanout {
+	return &ResultFanout{in: in}
+}
+
+// Subscribe 注册一个新订阅者并返回其专属的只读 channel；name 用于 Stats 中标识该订阅者，
+// bufSize 为其 channel 缓冲区大小。必须在 Run 启动前调用完所有 Subscribe。
+func (f *ResultFanout) Subscribe(name string, bufSize int) <-chan *DetectionResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sub := &fanoutSubscriber{name: name, ch: make(chan *DetectionResult, bufSize)}
+	f.subscribers = append(f.subscribers, sub)
+	return sub.ch
+}
+
+// Run 从 in 读取结果并分发给所有订阅者，直到 in 被关闭；应以单独协程运行。
+// 订阅者 channel 已满时丢弃该份拷贝并计入 dropped，不阻塞其余订阅者。
+func (f *ResultFanout) Run() {
+	for result := range f.in {
+		f.mu.Lock()
+		subs := f.subscribers
+		f.mu.Unlock()
+
+		for _, sub := range subs {
+			select {
+			case sub.ch <- result:
+				atomic.AddInt64(&sub.delivered, 1)
+			default:
+				atomic.AddInt64(&sub.dropped, 1)
+			}
+		}
+	}
+}
+
+// Stats 返回每个订阅者的投递/丢弃计数，供 /api/status 展示
+func (f *ResultFanout) Stats() map[string]interface{} {
+	f.mu.Lock()
+	subs := f.subscribers
+	f.mu.Unlock()
+
+	stats := make(map[string]interface{}, len(subs))
+	for _, sub := range subs {
+		stats[sub.name] = map[string]interface{}{
+			"delivered": atomic.LoadInt64(&sub.delivered),
+			"dropped":   atomic.LoadInt64(&sub.dropped),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,408 @@
+package training
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/model"
+	"anomaly_detection_system/backend/internal/pipeline"
+)
+
+const (
+	systemConfigScheduleKey = "training_schedule"
+	thresholdPollInterval   = 30 * time.Second // 样本阈值轮询间隔
+	jobQueueSize            = 8
+)
+
+// 训练触发来源
+const (
+	TriggerManual    = "manual"
+	TriggerCron      = "cron"
+	TriggerThreshold = "threshold"
+)
+
+// ScheduleEntry 一条定时训练计划
+type ScheduleEntry struct {
+	ID        uint      `json:"id"`
+	CronExpr  string    `json:"cron_expr"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// trainingJob 投递给 worker 协程的一次训练请求
+type trainingJob struct {
+	trigger string
+}
+
+// Scheduler 训练任务调度器：单个 worker 协程串行执行训练，
+// 同时接受手动触发（HTTP）、cron 定时触发、样本阈值触发三路输入，
+// 训练互斥依赖 worker 协程本身串行消费任务队列，无需额外加锁
+type Scheduler struct {
+	config  *config.Config
+	backend pipeline.DetectionBackend
+
+	cron    *cron.Cron
+	jobChan chan trainingJob
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu           sync.Mutex
+	running      bool
+	currentCmd   *exec.Cmd
+	currentLogID uint
+
+	schedMu     sync.Mutex
+	schedules   map[uint]ScheduleEntry
+	cronIDs     map[uint]cron.EntryID
+	nextSchedID uint
+
+	thresholdFired bool // 避免样本数持续高于阈值时重复触发
+}
+
+// NewScheduler 创建训练调度器
+func NewScheduler(cfg *config.Config, backend pipeline.DetectionBackend) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		config:    cfg,
+		backend:   backend,
+		cron:      cron.New(),
+		jobChan:   make(chan trainingJob, jobQueueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+		schedules: make(map[uint]ScheduleEntry),
+		cronIDs:   make(map[uint]cron.EntryID),
+	}
+}
+
+// Start 恢复已保存的定时计划，并启动 worker、cron 调度器与阈值轮询协程
+func (s *Scheduler) Start() error {
+	if err := s.loadSchedules(); err != nil {
+		log.Printf("[Scheduler] 加载定时训练计划失败: %v", err)
+	}
+
+	s.cron.Start()
+	go s.worker()
+	go s.thresholdLoop()
+
+	log.Println("[Scheduler] 训练调度器已启动")
+	return nil
+}
+
+// Stop 停止调度器
+func (s *Scheduler) Stop() {
+	s.cancel()
+	<-s.cron.Stop().Done()
+}
+
+// TriggerManual 手动触发一次训练
+func (s *Scheduler) TriggerManual() {
+	s.enqueue(TriggerManual)
+}
+
+// enqueue 将一次训练请求投递到任务队列，队列已满时丢弃并记录日志
+func (s *Scheduler) enqueue(trigger string) {
+	select {
+	case s.jobChan <- trainingJob{trigger: trigger}:
+	default:
+		log.Printf("[Scheduler] 训练任务队列已满，丢弃一次 %s 触发", trigger)
+	}
+}
+
+// worker 单协程串行处理训练任务
+func (s *Scheduler) worker() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case job := <-s.jobChan:
+			s.runTraining(job.trigger)
+		}
+	}
+}
+
+// thresholdLoop 定期检查已标注样本数是否跨过 TriggerThreshold
+func (s *Scheduler) thresholdLoop() {
+	ticker := time.NewTicker(thresholdPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := model.GetLabeledSamplesCount()
+			if err != nil {
+				log.Printf("[Scheduler] 查询已标注样本数失败: %v", err)
+				continue
+			}
+
+			threshold := int64(s.config.GetTraining().TriggerThreshold)
+			if count >= threshold {
+				if !s.thresholdFired {
+					s.thresholdFired = true
+					s.enqueue(TriggerThreshold)
+				}
+			} else {
+				s.thresholdFired = false
+			}
+		}
+	}
+}
+
+// runTraining 执行一次完整的训练流程：起子进程、落盘日志、更新训练记录，
+// 只有当数据库记录被标记为 completed 之后才会触发模型重载
+func (s *Scheduler) runTraining(trigger string) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		log.Printf("[Scheduler] 已有训练任务在运行，跳过 %s 触发", trigger)
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.currentCmd = nil
+		s.currentLogID = 0
+		s.mu.Unlock()
+	}()
+
+	trainingConfig := s.config.GetTraining()
+
+	sampleCount, err := model.GetLabeledSamplesCount()
+	if err != nil {
+		log.Printf("[Scheduler] 查询样本数失败: %v", err)
+	}
+
+	logDir := filepath.Dir(trainingConfig.ModelOutputPath)
+	if logDir == "" || logDir == "." {
+		logDir = "."
+	}
+	logPath := filepath.Join(logDir, fmt.Sprintf("train_%d.log", time.Now().Unix()))
+
+	trainingLog := &model.TrainingLog{
+		SampleCount: int(sampleCount),
+		StartTime:   time.Now(),
+		Status:      "running",
+		Trigger:     trigger,
+		LogFilePath: logPath,
+	}
+	if err := model.CreateTrainingLog(trainingLog); err != nil {
+		log.Printf("[Scheduler] 创建训练日志失败: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.currentLogID = trainingLog.ID
+	s.mu.Unlock()
+
+	log.Printf("[Scheduler] 开始训练 (id=%d, trigger=%s, script=%s)",
+		trainingLog.ID, trigger, trainingConfig.TrainingScriptPath)
+
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		s.finishWithError(trainingLog.ID, fmt.Errorf("创建训练日志文件失败: %w", err))
+		return
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command("python", trainingConfig.TrainingScriptPath)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true} // 独立进程组，便于取消时整组回收
+
+	s.mu.Lock()
+	s.currentCmd = cmd
+	s.mu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		s.finishWithError(trainingLog.ID, fmt.Errorf("启动训练脚本失败: %w", err))
+		return
+	}
+
+	runErr := cmd.Wait()
+	now := time.Now()
+
+	if runErr != nil {
+		status := "failed"
+		if exitErr, ok := runErr.(*exec.ExitError); ok {
+			if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				status = "cancelled"
+			}
+		}
+
+		model.UpdateTrainingLog(trainingLog.ID, map[string]interface{}{
+			"status":        status,
+			"end_time":      now,
+			"error_message": runErr.Error(),
+		})
+		log.Printf("[Scheduler] 训练 %d 结束: status=%s, err=%v", trainingLog.ID, status, runErr)
+		return
+	}
+
+	if err := model.UpdateTrainingLog(trainingLog.ID, map[string]interface{}{
+		"status":         "completed",
+		"end_time":       now,
+		"new_model_path": trainingConfig.ModelOutputPath,
+	}); err != nil {
+		log.Printf("[Scheduler] 更新训练记录失败: %v", err)
+		return
+	}
+
+	log.Printf("[Scheduler] 训练 %d 完成，重新加载模型", trainingLog.ID)
+
+	if s.backend != nil {
+		resp, err := s.backend.ReloadModel(trainingConfig.ModelOutputPath)
+		if err != nil {
+			log.Printf("[Scheduler] 模型重载失败: %v", err)
+		} else if resp != nil {
+			log.Printf("[Scheduler] 模型重载结果: %s", resp.Message)
+		}
+	}
+}
+
+// finishWithError 将训练记录标记为失败
+func (s *Scheduler) finishWithError(logID uint, err error) {
+	model.UpdateTrainingLog(logID, map[string]interface{}{
+		"status":        "failed",
+		"end_time":      time.Now(),
+		"error_message": err.Error(),
+	})
+	log.Printf("[Scheduler] 训练 %d 失败: %v", logID, err)
+}
+
+// CancelTraining 取消正在运行的训练任务，通过信号杀掉整个子进程组
+func (s *Scheduler) CancelTraining(logID uint) error {
+	s.mu.Lock()
+	cmd := s.currentCmd
+	curID := s.currentLogID
+	s.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil || curID != logID {
+		return fmt.Errorf("训练任务 %d 未在运行", logID)
+	}
+
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return fmt.Errorf("终止训练进程失败: %w", err)
+	}
+
+	return nil
+}
+
+// AddSchedule 新增一条 cron 定时训练计划并持久化
+func (s *Scheduler) AddSchedule(cronExpr string) (ScheduleEntry, error) {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	entryID, err := s.cron.AddFunc(cronExpr, func() { s.enqueue(TriggerCron) })
+	if err != nil {
+		return ScheduleEntry{}, fmt.Errorf("无效的 cron 表达式: %w", err)
+	}
+
+	s.nextSchedID++
+	entry := ScheduleEntry{
+		ID:        s.nextSchedID,
+		CronExpr:  cronExpr,
+		CreatedAt: time.Now(),
+	}
+
+	s.schedules[entry.ID] = entry
+	s.cronIDs[entry.ID] = entryID
+
+	if err := s.persistSchedulesLocked(); err != nil {
+		log.Printf("[Scheduler] 持久化定时计划失败: %v", err)
+	}
+
+	return entry, nil
+}
+
+// RemoveSchedule 删除一条定时训练计划
+func (s *Scheduler) RemoveSchedule(id uint) error {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	entryID, ok := s.cronIDs[id]
+	if !ok {
+		return fmt.Errorf("定时计划 %d 不存在", id)
+	}
+
+	s.cron.Remove(entryID)
+	delete(s.cronIDs, id)
+	delete(s.schedules, id)
+
+	return s.persistSchedulesLocked()
+}
+
+// ListSchedules 返回当前所有定时训练计划
+func (s *Scheduler) ListSchedules() []ScheduleEntry {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	entries := make([]ScheduleEntry, 0, len(s.schedules))
+	for _, e := range s.schedules {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// loadSchedules 从 SystemConfig 中恢复已保存的定时训练计划
+func (s *Scheduler) loadSchedules() error {
+	raw, err := model.GetSystemConfig(systemConfigScheduleKey)
+	if err != nil {
+		return err
+	}
+	if raw == "" {
+		return nil
+	}
+
+	var entries []ScheduleEntry
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return fmt.Errorf("解析定时训练计划失败: %w", err)
+	}
+
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	for _, entry := range entries {
+		entryID, err := s.cron.AddFunc(entry.CronExpr, func() { s.enqueue(TriggerCron) })
+		if err != nil {
+			log.Printf("[Scheduler] 恢复定时计划 %d 失败，表达式无效: %s", entry.ID, entry.CronExpr)
+			continue
+		}
+		s.schedules[entry.ID] = entry
+		s.cronIDs[entry.ID] = entryID
+		if entry.ID > s.nextSchedID {
+			s.nextSchedID = entry.ID
+		}
+	}
+
+	return nil
+}
+
+// persistSchedulesLocked 将当前定时计划写回 SystemConfig，调用方需持有 schedMu
+func (s *Scheduler) persistSchedulesLocked() error {
+	entries := make([]ScheduleEntry, 0, len(s.schedules))
+	for _, e := range s.schedules {
+		entries = append(entries, e)
+	}
+
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	return model.SetSystemConfig(systemConfigScheduleKey, string(raw))
+}
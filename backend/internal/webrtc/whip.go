@@ -0,0 +1,385 @@
+// Package webrtc 实现 WHIP (WebRTC-HTTP Ingestion Protocol) 推流端点，
+// 作为 ws 包 base64 JPEG WebSocket 推流之外的低延迟播放通道：视频走 H.264 RTP track，
+// 检测框叠加层走 DataChannel JSON 消息，前端可直接用 <video> 播放而不是逐帧 canvas 绘制
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pion/rtp"
+	pionwebrtc "github.com/pion/webrtc/v3"
+
+	"anomaly_detection_system/backend/internal/config"
+	"anomaly_detection_system/backend/internal/pipeline"
+	"anomaly_detection_system/backend/internal/ws"
+)
+
+// rtpBufferSize 单次 UDP 读取的缓冲区大小，足够容纳一个 RTP 包（MTU 以内）
+const rtpBufferSize = 1500
+
+// detectionOverlay 通过 DataChannel 下发的检测叠加层消息，形状与 ws.FrameMessage.Detections 一致，
+// 按 frame_id 关联，供前端把检测框绘制到 <video> 元素上方
+type detectionOverlay struct {
+	FrameID    int64               `json:"frame_id"`
+	Detections []*ws.DetectionData `json:"detections"`
+}
+
+// session 一路 WHIP 推流会话：一个 PeerConnection、一路 H.264 视频 track、
+// 一个承载检测叠加层的 DataChannel，以及喂给视频 track 的 ffmpeg 编码进程
+type session struct {
+	id       string
+	cameraID string
+
+	pc          *pionwebrtc.PeerConnection
+	videoTrack  *pionwebrtc.TrackLocalStaticRTP
+	dataChannel *pionwebrtc.DataChannel
+
+	cmd     *exec.Cmd
+	udpConn *net.UDPConn
+}
+
+// close 停止 ffmpeg 编码进程并关闭 PeerConnection，释放 UDP 端口
+func (s *session) close() {
+	if s.cmd != nil && s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+		s.cmd.Wait()
+	}
+	if s.udpConn != nil {
+		s.udpConn.Close()
+	}
+	if s.pc != nil {
+		s.pc.Close()
+	}
+}
+
+// Server 管理所有 WHIP 会话，并将 DetectionResult 按 CameraID 投递到对应会话的 DataChannel
+type Server struct {
+	config *config.Config
+
+	mu       sync.RWMutex
+	sessions map[string]*session // sessionID -> session
+
+	byCameraMu sync.RWMutex
+	byCamera   map[string][]*session // cameraID -> 该摄像头上所有存活的会话
+}
+
+// NewServer 创建 WHIP 服务器
+func NewServer(cfg *config.Config) *Server {
+	return &Server{
+		config:   cfg,
+		sessions: make(map[string]*session),
+		byCamera: make(map[string][]*session),
+	}
+}
+
+// HandleOffer 处理 WHIP 推流请求：POST /webrtc/whip/:camera_id，body 为 SDP offer 纯文本，
+// 响应 201 + SDP answer，并通过 Location 头返回供后续 DELETE 使用的资源地址
+func (s *Server) HandleOffer(c *gin.Context) {
+	cameraID := c.Param("camera_id")
+
+	offerSDP, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取 SDP offer 失败: " + err.Error()})
+		return
+	}
+
+	answerSDP, sess, err := s.createSession(cameraID, string(offerSDP))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立 WHIP 会话失败: " + err.Error()})
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/webrtc/whip/%s/%s", cameraID, sess.id))
+	c.Data(http.StatusCreated, "application/sdp", []byte(answerSDP))
+}
+
+// HandleDelete 处理 WHIP 推流停止请求：DELETE /webrtc/whip/:camera_id/:session_id
+func (s *Server) HandleDelete(c *gin.Context) {
+	sessionID := c.Param("session_id")
+
+	s.mu.Lock()
+	sess, ok := s.sessions[sessionID]
+	if ok {
+		delete(s.sessions, sessionID)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		return
+	}
+
+	s.removeFromCamera(sess)
+	sess.close()
+	c.Status(http.StatusNoContent)
+}
+
+// createSession 为指定摄像头创建一路 WHIP 会话：起一个本地 UDP 监听接收 ffmpeg 编码出的 RTP 包，
+// 转发进 WebRTC 视频 track，再起 ffmpeg 子进程把摄像头采集源编码为 H.264 RTP 推给该端口
+func (s *Server) createSession(cameraID, offerSDP string) (string, *session, error) {
+	videoConfig, ok := s.config.GetCamera(cameraID)
+	if !ok {
+		// 单摄像头场景：退回 Video 字段，并补上请求里的 CameraID
+		videoConfig = s.config.GetVideo()
+		videoConfig.CameraID = cameraID
+	}
+
+	source, err := pipeline.BuildCaptureSource(videoConfig)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pc, err := pionwebrtc.NewPeerConnection(pionwebrtc.Configuration{})
+	if err != nil {
+		return "", nil, fmt.Errorf("创建 PeerConnection 失败: %w", err)
+	}
+
+	videoTrack, err := pionwebrtc.NewTrackLocalStaticRTP(
+		pionwebrtc.RTPCodecCapability{MimeType: pionwebrtc.MimeTypeH264},
+		"video", "camera-"+cameraID,
+	)
+	if err != nil {
+		pc.Close()
+		return "", nil, fmt.Errorf("创建视频 track 失败: %w", err)
+	}
+
+	rtpSender, err := pc.AddTrack(videoTrack)
+	if err != nil {
+		pc.Close()
+		return "", nil, fmt.Errorf("添加视频 track 失败: %w", err)
+	}
+	// 必须持续读取 RTCP，否则发送缓冲区会无限增长，这里丢弃即可
+	go func() {
+		buf := make([]byte, rtpBufferSize)
+		for {
+			if _, _, err := rtpSender.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	dataChannel, err := pc.CreateDataChannel("detections", nil)
+	if err != nil {
+		pc.Close()
+		return "", nil, fmt.Errorf("创建检测叠加层 DataChannel 失败: %w", err)
+	}
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	if err != nil {
+		pc.Close()
+		return "", nil, fmt.Errorf("监听 RTP 转发端口失败: %w", err)
+	}
+	rtpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
+
+	offer := pionwebrtc.SessionDescription{Type: pionwebrtc.SDPTypeOffer, SDP: offerSDP}
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		udpConn.Close()
+		pc.Close()
+		return "", nil, fmt.Errorf("设置远端 SDP 失败: %w", err)
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		udpConn.Close()
+		pc.Close()
+		return "", nil, fmt.Errorf("创建 SDP answer 失败: %w", err)
+	}
+
+	// WHIP 不支持 trickle ICE，必须等 ICE candidate 收集完毕后一次性把完整 SDP 返回给对端
+	gatherComplete := pionwebrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		udpConn.Close()
+		pc.Close()
+		return "", nil, fmt.Errorf("设置本地 SDP 失败: %w", err)
+	}
+	<-gatherComplete
+
+	cmd, err := startEncoder(source, videoConfig, rtpPort)
+	if err != nil {
+		udpConn.Close()
+		pc.Close()
+		return "", nil, fmt.Errorf("启动 H.264 编码器失败: %w", err)
+	}
+
+	sess := &session{
+		id:          uuid.NewString(),
+		cameraID:    cameraID,
+		pc:          pc,
+		videoTrack:  videoTrack,
+		dataChannel: dataChannel,
+		cmd:         cmd,
+		udpConn:     udpConn,
+	}
+
+	go forwardRTP(udpConn, videoTrack)
+
+	s.mu.Lock()
+	s.sessions[sess.id] = sess
+	s.mu.Unlock()
+
+	s.byCameraMu.Lock()
+	s.byCamera[cameraID] = append(s.byCamera[cameraID], sess)
+	s.byCameraMu.Unlock()
+
+	// 连接断开（ICE failed/closed）时清理会话，避免僵死的 ffmpeg 进程和 UDP 监听
+	pc.OnConnectionStateChange(func(state pionwebrtc.PeerConnectionState) {
+		if state == pionwebrtc.PeerConnectionStateFailed || state == pionwebrtc.PeerConnectionStateClosed {
+			s.mu.Lock()
+			delete(s.sessions, sess.id)
+			s.mu.Unlock()
+			s.removeFromCamera(sess)
+			sess.close()
+		}
+	})
+
+	return pc.LocalDescription().SDP, sess, nil
+}
+
+// removeFromCamera 把会话从 byCamera 索引中摘除
+func (s *Server) removeFromCamera(sess *session) {
+	s.byCameraMu.Lock()
+	defer s.byCameraMu.Unlock()
+
+	sessions := s.byCamera[sess.cameraID]
+	for i, candidate := range sessions {
+		if candidate == sess {
+			s.byCamera[sess.cameraID] = append(sessions[:i], sessions[i+1:]...)
+			break
+		}
+	}
+}
+
+// forwardRTP 持续从 UDP 端口读取 ffmpeg 编码出的 RTP 包并写入视频 track
+func forwardRTP(conn *net.UDPConn, track *pionwebrtc.TrackLocalStaticRTP) {
+	buf := make([]byte, rtpBufferSize)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		if err := track.WriteRTP(packet); err != nil {
+			return
+		}
+	}
+}
+
+// startEncoder 拉起 ffmpeg 子进程，把 source 实时转码为 H.264 RTP 并发往 127.0.0.1:rtpPort。
+// 负载类型固定为 96，与 pion 在 answer SDP 中为 H264 协商的动态负载类型保持一致
+func startEncoder(source string, videoConfig config.VideoConfig, rtpPort int) (*exec.Cmd, error) {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+	}
+
+	if videoConfig.SourceType == "rtsp" && videoConfig.Transport != "" && videoConfig.Transport != "auto" {
+		args = append(args, "-rtsp_transport", videoConfig.Transport)
+	}
+	if videoConfig.SourceType == "local" {
+		args = append(args, "-stream_loop", "-1")
+	}
+
+	args = append(args,
+		"-i", source,
+		"-c:v", "libx264",
+		"-tune", "zerolatency",
+		"-payload_type", "96",
+		"-f", "rtp",
+		"rtp://127.0.0.1:"+strconv.Itoa(rtpPort),
+	)
+
+	cmd := exec.Command("ffmpeg", args...)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[WHIP] ffmpeg 编码器已启动，source=%s, rtp_port=%d", source, rtpPort)
+	return cmd, nil
+}
+
+// PublishDetections 把一帧检测结果投递给对应摄像头上所有 WHIP 会话的 DataChannel，
+// 未开启任何会话（没有观看端）时直接跳过，不做缓冲
+func (s *Server) PublishDetections(result *pipeline.DetectionResult) {
+	if result == nil || result.Frame == nil {
+		return
+	}
+
+	s.byCameraMu.RLock()
+	sessions := s.byCamera[result.Frame.CameraID]
+	s.byCameraMu.RUnlock()
+
+	if len(sessions) == 0 {
+		return
+	}
+
+	overlay := detectionOverlay{FrameID: result.FrameID, Detections: make([]*ws.DetectionData, 0, len(result.Detections))}
+	for _, det := range result.Detections {
+		overlay.Detections = append(overlay.Detections, &ws.DetectionData{
+			ID:          det.ID,
+			X1:          det.X1,
+			Y1:          det.Y1,
+			X2:          det.X2,
+			Y2:          det.Y2,
+			ClassName:   det.ClassName,
+			ClassID:     det.ClassID,
+			Confidence:  det.Confidence,
+			Entropy:     det.Entropy,
+			IsUncertain: det.IsUncertain,
+		})
+	}
+
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		log.Printf("[WHIP] 检测叠加层序列化失败: %v", err)
+		return
+	}
+
+	for _, sess := range sessions {
+		if sess.dataChannel.ReadyState() != pionwebrtc.DataChannelStateOpen {
+			continue
+		}
+		if err := sess.dataChannel.Send(data); err != nil {
+			log.Printf("[WHIP] 会话 %s 检测叠加层发送失败: %v", sess.id, err)
+		}
+	}
+}
+
+// Run 持续消费检测结果并分发给各会话，应作为独立协程启动
+func (s *Server) Run(resultChan <-chan *pipeline.DetectionResult) {
+	for result := range resultChan {
+		s.PublishDetections(result)
+	}
+}
+
+// Stop 关闭所有会话
+func (s *Server) Stop() {
+	s.mu.Lock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.sessions = make(map[string]*session)
+	s.mu.Unlock()
+
+	s.byCameraMu.Lock()
+	s.byCamera = make(map[string][]*session)
+	s.byCameraMu.Unlock()
+
+	for _, sess := range sessions {
+		sess.close()
+	}
+}
@@ -3,7 +3,9 @@ package ws
 import (
 	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
@@ -49,6 +51,7 @@ type DetectionData struct {
 // AlertMessage 报警消息
 type AlertMessage struct {
 	ID         int32   `json:"id"`
+	CameraID   string  `json:"camera_id"` // 来源摄像头 ID，单摄像头场景下为空
 	FrameID    int64   `json:"frame_id"`
 	Timestamp  int64   `json:"timestamp"`
 	ImageData  string  `json:"image_data"` // 裁剪后的截图 (Base64)
@@ -59,13 +62,44 @@ type AlertMessage struct {
 	ClassName  string  `json:"class_name"`
 	Confidence float32 `json:"confidence"`
 	Entropy    float32 `json:"entropy"`
+	StrategyID uint    `json:"strategy_id"` // 命中的报警策略 ID，0 表示内置默认策略
+	Severity   string  `json:"severity"`    // 报警级别，来自命中策略的 Severity 字段
+	EventType  string  `json:"event_type"`  // "firing"（轨迹首次持续满足条件）或 "resolved"（轨迹消失后的恢复事件）
 }
 
+// clientMessage 投递给单个客户端的一条消息，kind 决定 writePump 用 MessageText 还是 MessageBinary 发送
+type clientMessage struct {
+	kind websocket.MessageType
+	data []byte
+}
+
+// wireMessage 同一条广播消息的两种编码：json 供文本格式客户端使用，binary 可能为 nil
+// （例如该消息暂未实现二进制编码），此时请求二进制格式的客户端会回退到 json
+type wireMessage struct {
+	json   []byte
+	binary []byte
+}
+
+// forClient 按客户端的 format 选出应发送的数据及对应的 WebSocket 消息类型
+func (m *wireMessage) forClient(format string) clientMessage {
+	if format == formatBinary && m.binary != nil {
+		return clientMessage{kind: websocket.MessageBinary, data: m.binary}
+	}
+	return clientMessage{kind: websocket.MessageText, data: m.json}
+}
+
+// formatJSON/formatBinary 客户端通过 ?format= 查询参数选择的推流格式
+const (
+	formatJSON   = "json"
+	formatBinary = "binary"
+)
+
 // Client WebSocket 客户端
 type Client struct {
 	conn   *websocket.Conn
-	send   chan []byte
+	send   chan clientMessage
 	hub    *Hub
+	format string // formatJSON 或 formatBinary，连接建立时确定
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -74,7 +108,7 @@ type Client struct {
 type Hub struct {
 	mu         sync.RWMutex
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan *wireMessage
 	register   chan *Client
 	unregister chan *Client
 	ctx        context.Context
@@ -86,7 +120,7 @@ func NewHub() *Hub {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan *wireMessage, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		ctx:        ctx,
@@ -113,11 +147,11 @@ func (h *Hub) Run() {
 			}
 			h.mu.Unlock()
 			log.Printf("[WebSocket] 客户端断开，当前连接数: %d", len(h.clients))
-		case message := <-h.broadcast:
+		case msg := <-h.broadcast:
 			h.mu.RLock()
 			for client := range h.clients {
 				select {
-				case client.send <- message:
+				case client.send <- msg.forClient(client.format):
 				default:
 					// 客户端发送缓冲区满，关闭连接
 					close(client.send)
@@ -141,8 +175,9 @@ func (h *Hub) Stop() {
 	h.mu.Unlock()
 }
 
-// Broadcast 广播消息
-func (h *Hub) Broadcast(msg *BroadcastMessage) {
+// Broadcast 广播消息。binary 为该消息预先编码好的二进制版本，可为 nil（此时二进制格式
+// 客户端会收到 json 作为回退）；两种编码都只在这里生成一次，不会因为客户端格式不同而重复编码
+func (h *Hub) Broadcast(msg *BroadcastMessage, binary []byte) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("[WebSocket] 消息序列化失败: %v", err)
@@ -150,7 +185,7 @@ func (h *Hub) Broadcast(msg *BroadcastMessage) {
 	}
 
 	select {
-	case h.broadcast <- data:
+	case h.broadcast <- &wireMessage{json: data, binary: binary}:
 	default:
 		log.Println("[WebSocket] 广播通道已满")
 	}
@@ -167,12 +202,12 @@ func (h *Hub) ClientCount() int {
 type WebSocketServer struct {
 	hub        *Hub
 	config     *config.Config
-	resultChan chan *pipeline.DetectionResult
+	resultChan <-chan *pipeline.DetectionResult
 	alertChan  chan *AlertMessage
 }
 
 // NewWebSocketServer 创建 WebSocket 服务器
-func NewWebSocketServer(cfg *config.Config, resultChan chan *pipeline.DetectionResult, alertChan chan *AlertMessage) *WebSocketServer {
+func NewWebSocketServer(cfg *config.Config, resultChan <-chan *pipeline.DetectionResult, alertChan chan *AlertMessage) *WebSocketServer {
 	return &WebSocketServer{
 		hub:        NewHub(),
 		config:     cfg,
@@ -233,12 +268,19 @@ func (ws *WebSocketServer) broadcastLoop() {
 			})
 		}
 
+		timestamp := time.Now().UnixMilli()
+
+		binary, err := encodeBinaryFrame(binaryTypeFrame, result.FrameID, timestamp, frameMsg.Width, frameMsg.Height, frameMsg.Detections, result.Frame.Data)
+		if err != nil {
+			log.Printf("[WebSocket] 二进制帧编码失败: %v", err)
+		}
+
 		// 广播
 		ws.hub.Broadcast(&BroadcastMessage{
 			Type:      "frame",
-			Timestamp: time.Now().UnixMilli(),
+			Timestamp: timestamp,
 			Data:      frameMsg,
-		})
+		}, binary)
 	}
 }
 
@@ -249,14 +291,87 @@ func (ws *WebSocketServer) alertLoop() {
 			continue
 		}
 
+		imageData, err := base64.StdEncoding.DecodeString(alert.ImageData)
+		if err != nil {
+			log.Printf("[WebSocket] 报警截图解码失败: %v", err)
+		}
+
+		binary, err := encodeBinaryFrame(binaryTypeAlert, alert.FrameID, alert.Timestamp, 0, 0, alertDetectionPayload{
+			ID:         alert.ID,
+			CameraID:   alert.CameraID,
+			X1:         alert.X1,
+			Y1:         alert.Y1,
+			X2:         alert.X2,
+			Y2:         alert.Y2,
+			ClassName:  alert.ClassName,
+			Confidence: alert.Confidence,
+			Entropy:    alert.Entropy,
+			StrategyID: alert.StrategyID,
+			Severity:   alert.Severity,
+			EventType:  alert.EventType,
+		}, imageData)
+		if err != nil {
+			log.Printf("[WebSocket] 二进制报警编码失败: %v", err)
+		}
+
 		ws.hub.Broadcast(&BroadcastMessage{
 			Type:      "alert",
 			Timestamp: time.Now().UnixMilli(),
 			Data:      alert,
-		})
+		}, binary)
 	}
 }
 
+// alertDetectionPayload 报警在二进制协议里对应的 detections JSON 字段，不含 ImageData（已放入 JPEG 字段，避免重复编码）
+type alertDetectionPayload struct {
+	ID         int32   `json:"id"`
+	CameraID   string  `json:"camera_id"`
+	X1         float32 `json:"x1"`
+	Y1         float32 `json:"y1"`
+	X2         float32 `json:"x2"`
+	Y2         float32 `json:"y2"`
+	ClassName  string  `json:"class_name"`
+	Confidence float32 `json:"confidence"`
+	Entropy    float32 `json:"entropy"`
+	StrategyID uint    `json:"strategy_id"`
+	Severity   string  `json:"severity"`
+	EventType  string  `json:"event_type"`
+}
+
+// 二进制帧协议：magic(4) + version(1) + type(1) + frame_id(8) + timestamp_ms(8) + width(2) + height(2)
+// + detections_len(4) + detections_json(N) + jpeg_len(4) + jpeg(M)，全部整数字段使用大端序，
+// 用于取代 JSON + Base64 路径，省去 Base64 膨胀与 JSON 转义开销
+var binaryMagic = [4]byte{'A', 'D', 'S', 'F'}
+
+const binaryProtocolVersion byte = 1
+
+const (
+	binaryTypeFrame byte = 0
+	binaryTypeAlert byte = 1
+)
+
+// encodeBinaryFrame 按二进制协议头拼出一帧完整的二进制消息，detections 会被序列化为 JSON 后原样嵌入
+func encodeBinaryFrame(msgType byte, frameID int64, timestampMs int64, width, height int, detections interface{}, jpeg []byte) ([]byte, error) {
+	detJSON, err := json.Marshal(detections)
+	if err != nil {
+		return nil, fmt.Errorf("序列化 detections 失败: %w", err)
+	}
+
+	buf := make([]byte, 0, 4+1+1+8+8+2+2+4+len(detJSON)+4+len(jpeg))
+	buf = append(buf, binaryMagic[:]...)
+	buf = append(buf, binaryProtocolVersion)
+	buf = append(buf, msgType)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(frameID))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(timestampMs))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(width))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(height))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(detJSON)))
+	buf = append(buf, detJSON...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(jpeg)))
+	buf = append(buf, jpeg...)
+	return buf, nil
+}
+
 // HandleWebSocket 处理 WebSocket 连接
 func (ws *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
@@ -270,10 +385,16 @@ func (ws *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
+	format := formatJSON
+	if r.URL.Query().Get("format") == formatBinary {
+		format = formatBinary
+	}
+
 	client := &Client{
 		conn:   conn,
-		send:   make(chan []byte, 256),
+		send:   make(chan clientMessage, 256),
 		hub:    ws.hub,
+		format: format,
 		ctx:    ctx,
 		cancel: cancel,
 	}
@@ -329,7 +450,7 @@ func (c *Client) writePump() {
 			}
 
 			ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
-			err := c.conn.Write(ctx, websocket.MessageText, message)
+			err := c.conn.Write(ctx, message.kind, message.data)
 			cancel()
 
 			if err != nil {